@@ -0,0 +1,63 @@
+package analytics
+
+// Streak 记录一段连续超过慢区块阈值的区间，写入 slow_block_streaks 集合。
+type Streak struct {
+	StartHeight   int64   `bson:"startHeight" json:"startHeight"`
+	EndHeight     int64   `bson:"endHeight" json:"endHeight"`
+	Count         int     `bson:"count" json:"count"`
+	TotalLostTime float64 `bson:"totalLostTime" json:"totalLostTime"`
+}
+
+// StreakTracker 在阈值之上的连续区间上累积 Streak。
+type StreakTracker struct {
+	minLength int
+	current   *Streak
+}
+
+// NewStreakTracker 创建一个 StreakTracker，只有长度达到 minLength 的区间
+// 才会在结束时被交出持久化。
+func NewStreakTracker(minLength int) *StreakTracker {
+	return &StreakTracker{minLength: minLength}
+}
+
+// Observe 处理一次区块间隔。aboveThreshold 表示该间隔是否超过慢区块阈值。
+// 当一段连续区间结束且长度达到 minLength 时，返回该 Streak 供调用方保存；
+// 否则返回 nil。
+func (t *StreakTracker) Observe(height int64, gap float64, aboveThreshold bool) *Streak {
+	if aboveThreshold {
+		if t.current == nil {
+			t.current = &Streak{StartHeight: height, EndHeight: height, Count: 1, TotalLostTime: gap}
+		} else {
+			t.current.EndHeight = height
+			t.current.Count++
+			t.current.TotalLostTime += gap
+		}
+		return nil
+	}
+
+	if t.current == nil {
+		return nil
+	}
+	finished := t.current
+	t.current = nil
+	if finished.Count >= t.minLength {
+		return finished
+	}
+	return nil
+}
+
+// Finish 结束当前仍在进行中的连续区间（如果有），供调用方在停止观测
+// 前（例如优雅退出或 --backfill 处理完所有区块）调用一次，避免一段
+// 正在进行中、长度已经达到 minLength 的慢区块区间因为从未等到一次
+// 低于阈值的间隔而永远不会被交出持久化。
+func (t *StreakTracker) Finish() *Streak {
+	if t.current == nil {
+		return nil
+	}
+	finished := t.current
+	t.current = nil
+	if finished.Count >= t.minLength {
+		return finished
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package analytics
+
+import "testing"
+
+func TestStreakTrackerBelowMinLengthIsDiscarded(t *testing.T) {
+	tracker := NewStreakTracker(3)
+
+	if streak := tracker.Observe(1, 10, true); streak != nil {
+		t.Fatalf("expected nil while streak is ongoing, got %+v", streak)
+	}
+	if streak := tracker.Observe(2, 10, true); streak != nil {
+		t.Fatalf("expected nil while streak is ongoing, got %+v", streak)
+	}
+	if streak := tracker.Observe(3, 1, false); streak != nil {
+		t.Fatalf("expected streak of length 2 to be discarded (minLength=3), got %+v", streak)
+	}
+}
+
+func TestStreakTrackerMeetsMinLength(t *testing.T) {
+	tracker := NewStreakTracker(2)
+
+	if streak := tracker.Observe(10, 5, true); streak != nil {
+		t.Fatalf("expected nil while streak is ongoing, got %+v", streak)
+	}
+	if streak := tracker.Observe(11, 6, true); streak != nil {
+		t.Fatalf("expected nil while streak is ongoing, got %+v", streak)
+	}
+
+	streak := tracker.Observe(12, 1, false)
+	if streak == nil {
+		t.Fatal("expected a finished streak once the run drops below threshold")
+	}
+	if streak.StartHeight != 10 || streak.EndHeight != 11 {
+		t.Fatalf("expected streak [10,11], got [%d,%d]", streak.StartHeight, streak.EndHeight)
+	}
+	if streak.Count != 2 {
+		t.Fatalf("expected count=2, got %d", streak.Count)
+	}
+	if streak.TotalLostTime != 11 {
+		t.Fatalf("expected totalLostTime=11, got %v", streak.TotalLostTime)
+	}
+}
+
+func TestStreakTrackerResetsAfterFinishing(t *testing.T) {
+	tracker := NewStreakTracker(1)
+
+	tracker.Observe(1, 5, true)
+	first := tracker.Observe(2, 0, false)
+	if first == nil {
+		t.Fatal("expected first streak to be returned")
+	}
+
+	tracker.Observe(3, 5, true)
+	second := tracker.Observe(4, 0, false)
+	if second == nil {
+		t.Fatal("expected second streak to be returned")
+	}
+	if second.StartHeight != 3 {
+		t.Fatalf("expected new streak to start at height 3, got %d", second.StartHeight)
+	}
+}
+
+func TestStreakTrackerFinishPersistsOngoingStreak(t *testing.T) {
+	tracker := NewStreakTracker(2)
+
+	tracker.Observe(100, 5, true)
+	tracker.Observe(101, 6, true)
+
+	streak := tracker.Finish()
+	if streak == nil {
+		t.Fatal("expected Finish to return the still-open streak that meets minLength")
+	}
+	if streak.StartHeight != 100 || streak.EndHeight != 101 || streak.Count != 2 {
+		t.Fatalf("unexpected streak from Finish: %+v", streak)
+	}
+
+	if again := tracker.Finish(); again != nil {
+		t.Fatalf("expected Finish to be a no-op once there's no open streak, got %+v", again)
+	}
+}
+
+func TestStreakTrackerFinishDiscardsShortOngoingStreak(t *testing.T) {
+	tracker := NewStreakTracker(3)
+
+	tracker.Observe(1, 5, true)
+	tracker.Observe(2, 5, true)
+
+	if streak := tracker.Finish(); streak != nil {
+		t.Fatalf("expected ongoing streak shorter than minLength to be discarded, got %+v", streak)
+	}
+}
+
+func TestStreakTrackerFinishWithNoOngoingStreak(t *testing.T) {
+	tracker := NewStreakTracker(1)
+	if streak := tracker.Finish(); streak != nil {
+		t.Fatalf("expected nil when there's nothing in progress, got %+v", streak)
+	}
+}
@@ -0,0 +1,78 @@
+// Package analytics 计算相邻区块提交时间间隔的滚动窗口统计量，并跟踪
+// 连续出现的慢区块区间。
+package analytics
+
+import (
+	"math"
+	"sort"
+)
+
+// IntervalStats 汇总一个滚动窗口内区块间隔的统计特征，写入
+// block_time_stats 集合，以触发该次计算的区块高度为键。
+type IntervalStats struct {
+	Height int64   `bson:"height" json:"height"`
+	Count  int     `bson:"count" json:"count"`
+	Mean   float64 `bson:"mean" json:"mean"`
+	P50    float64 `bson:"p50" json:"p50"`
+	P95    float64 `bson:"p95" json:"p95"`
+	P99    float64 `bson:"p99" json:"p99"`
+	StdDev float64 `bson:"stddev" json:"stddev"`
+}
+
+// Window 维护最近 size 个区块间隔，用于滚动统计。
+type Window struct {
+	size      int
+	intervals []float64
+}
+
+// NewWindow 创建一个容量为 size 的滚动窗口。
+func NewWindow(size int) *Window {
+	return &Window{size: size}
+}
+
+// Add 记录一个新的区块间隔（秒），超出窗口容量时丢弃最旧的样本。
+func (w *Window) Add(interval float64) {
+	w.intervals = append(w.intervals, interval)
+	if len(w.intervals) > w.size {
+		w.intervals = w.intervals[len(w.intervals)-w.size:]
+	}
+}
+
+// Stats 基于当前窗口内的样本计算统计量，height 是触发本次计算的区块高度。
+func (w *Window) Stats(height int64) IntervalStats {
+	n := len(w.intervals)
+	if n == 0 {
+		return IntervalStats{Height: height}
+	}
+
+	sorted := append([]float64(nil), w.intervals...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	mean := sum / float64(n)
+
+	var variance float64
+	for _, v := range sorted {
+		diff := v - mean
+		variance += diff * diff
+	}
+	variance /= float64(n)
+
+	percentile := func(p float64) float64 {
+		idx := int(p * float64(n-1))
+		return sorted[idx]
+	}
+
+	return IntervalStats{
+		Height: height,
+		Count:  n,
+		Mean:   mean,
+		P50:    percentile(0.50),
+		P95:    percentile(0.95),
+		P99:    percentile(0.99),
+		StdDev: math.Sqrt(variance),
+	}
+}
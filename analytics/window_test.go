@@ -0,0 +1,51 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWindowStatsEmpty(t *testing.T) {
+	w := NewWindow(5)
+	stats := w.Stats(10)
+	if stats.Height != 10 || stats.Count != 0 {
+		t.Fatalf("expected empty stats at height 10, got %+v", stats)
+	}
+}
+
+func TestWindowStatsBasic(t *testing.T) {
+	w := NewWindow(10)
+	for _, v := range []float64{1, 2, 3, 4, 5} {
+		w.Add(v)
+	}
+
+	stats := w.Stats(42)
+	if stats.Count != 5 {
+		t.Fatalf("expected count=5, got %d", stats.Count)
+	}
+	if stats.Mean != 3 {
+		t.Fatalf("expected mean=3, got %v", stats.Mean)
+	}
+	wantStdDev := math.Sqrt(2)
+	if math.Abs(stats.StdDev-wantStdDev) > 1e-9 {
+		t.Fatalf("expected stddev=%v, got %v", wantStdDev, stats.StdDev)
+	}
+	if stats.P50 != 3 {
+		t.Fatalf("expected p50=3, got %v", stats.P50)
+	}
+}
+
+func TestWindowDropsOldestSampleBeyondCapacity(t *testing.T) {
+	w := NewWindow(3)
+	for _, v := range []float64{1, 2, 3, 4} {
+		w.Add(v)
+	}
+
+	stats := w.Stats(1)
+	if stats.Count != 3 {
+		t.Fatalf("expected window capped at 3 samples, got %d", stats.Count)
+	}
+	if stats.Mean != 3 {
+		t.Fatalf("expected mean over [2,3,4]=3, got %v", stats.Mean)
+	}
+}
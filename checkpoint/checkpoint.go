@@ -0,0 +1,94 @@
+// Package checkpoint 持久化每个被监听日志文件的读取进度，使
+// SKIP_HISTORICAL_LOGS=true 重启时可以从上次退出的位置继续读取，而不是
+// 重新跳到文件末尾、丢失关闭期间产生的数据。
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/xueqianLu/NodeLogInsight/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collection 是 MongoDB / LevelDB 后端中保存检查点的集合名。
+const Collection = "_ingest_checkpoints"
+
+// FileCheckpoint 记录单个日志文件的读取进度。
+type FileCheckpoint struct {
+	Path   string `bson:"path" json:"path"`
+	Inode  uint64 `bson:"inode" json:"inode"`
+	Size   int64  `bson:"size" json:"size"`
+	Offset int64  `bson:"offset" json:"offset"`
+}
+
+// Store 在给定的 store.Store 后端上保存和恢复 FileCheckpoint。
+type Store struct {
+	backend store.Store
+}
+
+// New 创建一个以 backend 为底层存储的检查点 Store。
+func New(backend store.Store) *Store {
+	return &Store{backend: backend}
+}
+
+// Save 持久化 cp，同名路径的已有检查点会被覆盖。
+func (s *Store) Save(cp FileCheckpoint) error {
+	switch backend := s.backend.(type) {
+	case *store.MongoStore:
+		return saveMongo(backend.DB(), cp)
+	case *store.LevelDBStore:
+		return backend.Put(Collection, []byte(cp.Path), cp)
+	default:
+		return fmt.Errorf("存储后端 %T 不支持检查点持久化", s.backend)
+	}
+}
+
+// Load 读取 path 对应的检查点，found 为 false 表示此前没有保存过。
+func (s *Store) Load(path string) (cp FileCheckpoint, found bool, err error) {
+	switch backend := s.backend.(type) {
+	case *store.MongoStore:
+		return loadMongo(backend.DB(), path)
+	case *store.LevelDBStore:
+		return loadLevelDB(backend, path)
+	default:
+		return FileCheckpoint{}, false, fmt.Errorf("存储后端 %T 不支持检查点持久化", s.backend)
+	}
+}
+
+func saveMongo(db *mongo.Database, cp FileCheckpoint) error {
+	_, err := db.Collection(Collection).UpdateOne(
+		context.Background(),
+		bson.M{"path": cp.Path},
+		bson.M{"$set": cp},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func loadMongo(db *mongo.Database, path string) (FileCheckpoint, bool, error) {
+	var cp FileCheckpoint
+	err := db.Collection(Collection).FindOne(context.Background(), bson.M{"path": path}).Decode(&cp)
+	if err == mongo.ErrNoDocuments {
+		return FileCheckpoint{}, false, nil
+	}
+	if err != nil {
+		return FileCheckpoint{}, false, err
+	}
+	return cp, true, nil
+}
+
+func loadLevelDB(backend *store.LevelDBStore, path string) (FileCheckpoint, bool, error) {
+	data, ok, err := backend.Get(Collection, []byte(path))
+	if err != nil || !ok {
+		return FileCheckpoint{}, false, err
+	}
+	var cp FileCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return FileCheckpoint{}, false, err
+	}
+	return cp, true, nil
+}
@@ -0,0 +1,89 @@
+package checkpoint
+
+import (
+	"testing"
+
+	"github.com/xueqianLu/NodeLogInsight/store"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	backend, err := store.NewLevelDBStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error opening LevelDB store: %v", err)
+	}
+	t.Cleanup(func() { backend.Close() })
+	return New(backend)
+}
+
+func TestCheckpointSaveLoadRoundTrip(t *testing.T) {
+	s := newTestStore(t)
+
+	cp := FileCheckpoint{Path: "/var/log/node.log", Inode: 111, Size: 2048, Offset: 1024}
+	if err := s.Save(cp); err != nil {
+		t.Fatalf("unexpected error saving checkpoint: %v", err)
+	}
+
+	loaded, found, err := s.Load(cp.Path)
+	if err != nil {
+		t.Fatalf("unexpected error loading checkpoint: %v", err)
+	}
+	if !found {
+		t.Fatal("expected a previously saved checkpoint to be found")
+	}
+	if loaded != cp {
+		t.Fatalf("expected loaded checkpoint to equal saved checkpoint, got %+v want %+v", loaded, cp)
+	}
+}
+
+func TestCheckpointLoadNotFound(t *testing.T) {
+	s := newTestStore(t)
+
+	_, found, err := s.Load("/var/log/never-saved.log")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if found {
+		t.Fatal("expected found=false for a path that was never saved")
+	}
+}
+
+// TestCheckpointSavePreservesNewInodeAfterRotation 模拟日志轮换场景：
+// 同一路径先后对应不同的 inode。Save/Load 必须如实保留每次写入的
+// Inode，调用方（main.go 的 resumeOffset）才能通过比较当前文件的 inode
+// 与检查点里保存的 Inode 判断文件是否已经被轮换，从而决定是续读还是
+// 从头开始。
+func TestCheckpointSavePreservesNewInodeAfterRotation(t *testing.T) {
+	s := newTestStore(t)
+	path := "/var/log/node.log"
+
+	original := FileCheckpoint{Path: path, Inode: 111, Size: 4096, Offset: 4096}
+	if err := s.Save(original); err != nil {
+		t.Fatalf("unexpected error saving original checkpoint: %v", err)
+	}
+
+	loaded, found, err := s.Load(path)
+	if err != nil || !found {
+		t.Fatalf("unexpected load result: found=%v err=%v", found, err)
+	}
+	if loaded.Inode != original.Inode {
+		t.Fatalf("expected loaded inode=%d, got %d", original.Inode, loaded.Inode)
+	}
+
+	// 文件被轮换：同一路径，新的 inode，offset 重新从 0 开始。
+	rotated := FileCheckpoint{Path: path, Inode: 222, Size: 0, Offset: 0}
+	if err := s.Save(rotated); err != nil {
+		t.Fatalf("unexpected error saving rotated checkpoint: %v", err)
+	}
+
+	loaded, found, err = s.Load(path)
+	if err != nil || !found {
+		t.Fatalf("unexpected load result after rotation: found=%v err=%v", found, err)
+	}
+	if loaded.Inode == original.Inode {
+		t.Fatal("expected the rotated checkpoint's inode to differ from the original, but it didn't change")
+	}
+	if loaded != rotated {
+		t.Fatalf("expected the rotated checkpoint to fully replace the original, got %+v want %+v", loaded, rotated)
+	}
+}
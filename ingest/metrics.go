@@ -0,0 +1,71 @@
+package ingest
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// Metrics 持有以 Prometheus 文本格式暴露的摄入管道计数器。
+type Metrics struct {
+	linesParsed    uint64
+	docsInserted   uint64
+	dupSkipped     uint64
+	batchLatencyMs uint64 // 累计批次耗时（毫秒），与 batchCount 搭配计算平均值
+	batchCount     uint64
+}
+
+// NewMetrics 创建一组初始为零的计数器。
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+func (m *Metrics) AddLinesParsed(n uint64)  { atomic.AddUint64(&m.linesParsed, n) }
+func (m *Metrics) AddDocsInserted(n uint64) { atomic.AddUint64(&m.docsInserted, n) }
+func (m *Metrics) AddDupSkipped(n uint64)   { atomic.AddUint64(&m.dupSkipped, n) }
+
+// ObserveBatchLatency 记录一次批量写入耗时，单位毫秒。
+func (m *Metrics) ObserveBatchLatency(ms uint64) {
+	atomic.AddUint64(&m.batchLatencyMs, ms)
+	atomic.AddUint64(&m.batchCount, 1)
+}
+
+// Handler 返回一个以 Prometheus 文本暴露格式输出当前计数器的 http.Handler。
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+		batchCount := atomic.LoadUint64(&m.batchCount)
+		var avgLatency float64
+		if batchCount > 0 {
+			avgLatency = float64(atomic.LoadUint64(&m.batchLatencyMs)) / float64(batchCount)
+		}
+
+		fmt.Fprintf(w, "# HELP nodeloginsight_lines_parsed_total 已解析的日志行数\n")
+		fmt.Fprintf(w, "# TYPE nodeloginsight_lines_parsed_total counter\n")
+		fmt.Fprintf(w, "nodeloginsight_lines_parsed_total %d\n", atomic.LoadUint64(&m.linesParsed))
+
+		fmt.Fprintf(w, "# HELP nodeloginsight_docs_inserted_total 已写入 MongoDB 的文档数\n")
+		fmt.Fprintf(w, "# TYPE nodeloginsight_docs_inserted_total counter\n")
+		fmt.Fprintf(w, "nodeloginsight_docs_inserted_total %d\n", atomic.LoadUint64(&m.docsInserted))
+
+		fmt.Fprintf(w, "# HELP nodeloginsight_dup_skipped_total 因重复键被跳过的文档数\n")
+		fmt.Fprintf(w, "# TYPE nodeloginsight_dup_skipped_total counter\n")
+		fmt.Fprintf(w, "nodeloginsight_dup_skipped_total %d\n", atomic.LoadUint64(&m.dupSkipped))
+
+		fmt.Fprintf(w, "# HELP nodeloginsight_batch_latency_ms_avg 批量写入平均耗时（毫秒）\n")
+		fmt.Fprintf(w, "# TYPE nodeloginsight_batch_latency_ms_avg gauge\n")
+		fmt.Fprintf(w, "nodeloginsight_batch_latency_ms_avg %f\n", avgLatency)
+	})
+}
+
+// Serve 在独立的 goroutine 中启动一个暴露 /metrics 的 HTTP 服务。
+func (m *Metrics) Serve(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			fmt.Printf("指标 HTTP 服务退出: %v\n", err)
+		}
+	}()
+}
@@ -0,0 +1,190 @@
+// Package ingest 实现了一个带缓冲的批量写入管道：事件先进入一个队列，
+// 再由一组 worker 按集合分批，通过 BulkWrite 写入 MongoDB。
+package ingest
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WriteRequest 是提交给摄入管道的一条待写入文档。
+type WriteRequest struct {
+	Collection string
+	Document   interface{}
+}
+
+// Writer 是一个基于 worker 池的批量写入管道。
+type Writer struct {
+	db            *mongo.Database
+	queue         chan WriteRequest
+	batchSize     int
+	flushInterval time.Duration
+	metrics       *Metrics
+
+	wg        sync.WaitGroup
+	pending   int64
+	flushReqs []chan struct{}
+}
+
+// NewWriter 创建一个 Writer。workers 与 batchSize 的乘积决定队列容量，实际
+// 启动的 worker 数量由 Start 的参数决定；batchSize 和 flushInterval 控制
+// 每个 worker 攒批写入 MongoDB 的节奏。
+func NewWriter(db *mongo.Database, workers, batchSize int, flushInterval time.Duration, metrics *Metrics) *Writer {
+	return &Writer{
+		db:            db,
+		queue:         make(chan WriteRequest, batchSize*workers),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		metrics:       metrics,
+	}
+}
+
+// Start 启动 worker 池。
+func (w *Writer) Start(workers int) {
+	w.flushReqs = make([]chan struct{}, workers)
+	for i := 0; i < workers; i++ {
+		w.flushReqs[i] = make(chan struct{}, 1)
+		w.wg.Add(1)
+		go w.runWorker(w.flushReqs[i])
+	}
+}
+
+// Enqueue 将一个待写入文档放入队列，由 worker 异步批量落库。
+func (w *Writer) Enqueue(collection string, doc interface{}) {
+	atomic.AddInt64(&w.pending, 1)
+	w.queue <- WriteRequest{Collection: collection, Document: doc}
+}
+
+// Flush 强制所有 worker 立即刷新各自缓冲区，并阻塞到目前为止入队的文档
+// 全部完成 BulkWrite（无论成功、重复键还是失败，只要结果已经落定）。
+// 调用方（例如 checkpoint 持久化）必须在 Flush 返回后再推进检查点，
+// 否则进程崩溃可能丢失已经确认但实际尚未落库的文档。
+func (w *Writer) Flush() {
+	for _, ch := range w.flushReqs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+	for atomic.LoadInt64(&w.pending) > 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// Close 停止接收新请求，等待所有 worker 把剩余缓冲刷新完毕。
+func (w *Writer) Close() {
+	close(w.queue)
+	w.wg.Wait()
+}
+
+func (w *Writer) runWorker(flushReq chan struct{}) {
+	defer w.wg.Done()
+
+	buffers := make(map[string][]interface{})
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	flush := func(collection string) {
+		docs := buffers[collection]
+		if len(docs) == 0 {
+			return
+		}
+		w.bulkWrite(collection, docs)
+		atomic.AddInt64(&w.pending, -int64(len(docs)))
+		buffers[collection] = nil
+	}
+
+	flushAll := func() {
+		for collection := range buffers {
+			flush(collection)
+		}
+	}
+
+	for {
+		select {
+		case req, ok := <-w.queue:
+			if !ok {
+				flushAll()
+				return
+			}
+			buffers[req.Collection] = append(buffers[req.Collection], req.Document)
+			if len(buffers[req.Collection]) >= w.batchSize {
+				flush(req.Collection)
+			}
+		case <-ticker.C:
+			flushAll()
+		case <-flushReq:
+			flushAll()
+		}
+	}
+}
+
+// bulkWrite 把一批文档以 ordered=false 的方式写入指定集合，过滤掉重复键
+// 错误后把真实失败记录到日志中。
+func (w *Writer) bulkWrite(collection string, docs []interface{}) {
+	models := make([]mongo.WriteModel, 0, len(docs))
+	for _, doc := range docs {
+		models = append(models, mongo.NewInsertOneModel().SetDocument(doc))
+	}
+
+	start := time.Now()
+	_, err := w.db.Collection(collection).BulkWrite(
+		context.Background(),
+		models,
+		options.BulkWrite().SetOrdered(false),
+	)
+	latency := time.Since(start)
+	if w.metrics != nil {
+		w.metrics.ObserveBatchLatency(uint64(latency.Milliseconds()))
+	}
+
+	if err == nil {
+		if w.metrics != nil {
+			w.metrics.AddDocsInserted(uint64(len(docs)))
+		}
+		return
+	}
+
+	bwException, ok := err.(mongo.BulkWriteException)
+	if !ok {
+		log.Printf("批量写入 %s 失败: %v", collection, err)
+		return
+	}
+
+	dupCount, realFailures := splitDuplicateKeyErrors(bwException.WriteErrors)
+
+	inserted := len(docs) - dupCount - len(realFailures)
+	if w.metrics != nil {
+		if inserted > 0 {
+			w.metrics.AddDocsInserted(uint64(inserted))
+		}
+		if dupCount > 0 {
+			w.metrics.AddDupSkipped(uint64(dupCount))
+		}
+	}
+
+	for _, writeErr := range realFailures {
+		log.Printf("批量写入 %s 时出现非重复键错误: %v", collection, writeErr)
+	}
+}
+
+// splitDuplicateKeyErrors 把一批 BulkWrite 错误分成重复键错误（MongoDB
+// 错误码 11000，意味着该文档已经存在，可以安全忽略）和其它需要上报的
+// 真实失败，供 bulkWrite 决定指标统计和日志。拆成独立函数是为了不依赖
+// 真实的 MongoDB 连接就能对这段分类逻辑做单元测试。
+func splitDuplicateKeyErrors(writeErrors []mongo.BulkWriteError) (dupCount int, realFailures []mongo.BulkWriteError) {
+	for _, writeErr := range writeErrors {
+		if writeErr.Code == 11000 {
+			dupCount++
+			continue
+		}
+		realFailures = append(realFailures, writeErr)
+	}
+	return dupCount, realFailures
+}
@@ -0,0 +1,45 @@
+package ingest
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestSplitDuplicateKeyErrorsAllDuplicates(t *testing.T) {
+	writeErrors := []mongo.BulkWriteError{
+		{WriteError: mongo.WriteError{Code: 11000, Message: "duplicate key"}},
+		{WriteError: mongo.WriteError{Code: 11000, Message: "duplicate key"}},
+	}
+
+	dupCount, realFailures := splitDuplicateKeyErrors(writeErrors)
+	if dupCount != 2 {
+		t.Fatalf("expected dupCount=2, got %d", dupCount)
+	}
+	if len(realFailures) != 0 {
+		t.Fatalf("expected no real failures, got %v", realFailures)
+	}
+}
+
+func TestSplitDuplicateKeyErrorsMixed(t *testing.T) {
+	writeErrors := []mongo.BulkWriteError{
+		{WriteError: mongo.WriteError{Code: 11000, Message: "duplicate key"}},
+		{WriteError: mongo.WriteError{Code: 121, Message: "document failed validation"}},
+		{WriteError: mongo.WriteError{Code: 11000, Message: "duplicate key"}},
+	}
+
+	dupCount, realFailures := splitDuplicateKeyErrors(writeErrors)
+	if dupCount != 2 {
+		t.Fatalf("expected dupCount=2, got %d", dupCount)
+	}
+	if len(realFailures) != 1 || realFailures[0].Code != 121 {
+		t.Fatalf("expected a single real failure with code 121, got %v", realFailures)
+	}
+}
+
+func TestSplitDuplicateKeyErrorsEmpty(t *testing.T) {
+	dupCount, realFailures := splitDuplicateKeyErrors(nil)
+	if dupCount != 0 || realFailures != nil {
+		t.Fatalf("expected no dups and no real failures for an empty input, got dupCount=%d realFailures=%v", dupCount, realFailures)
+	}
+}
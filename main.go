@@ -3,30 +3,33 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/xueqianLu/NodeLogInsight/analytics"
+	"github.com/xueqianLu/NodeLogInsight/checkpoint"
+	"github.com/xueqianLu/NodeLogInsight/ingest"
+	"github.com/xueqianLu/NodeLogInsight/parsers"
+	"github.com/xueqianLu/NodeLogInsight/store"
+	"github.com/xueqianLu/NodeLogInsight/trigger"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// 为不同类型的日志定义数据结构
-type CommittedState struct {
-	Timestamp time.Time `bson:"timestamp"`
-	Module    string    `bson:"module"`
-	Height    int64     `bson:"height"`
-	Txs       int       `bson:"txs"`
-	AppHash   string    `bson:"appHash"`
-}
-
 // BlockTimeGap 记录相邻区块提交时间间隔超过阈值的情况
 type BlockTimeGap struct {
 	Timestamp      time.Time `bson:"timestamp"`      // 当前区块的时间戳
@@ -37,51 +40,334 @@ type BlockTimeGap struct {
 }
 
 // 全局变量，用于跟踪上一次提交的状态
-var lastCommittedState *CommittedState
+var lastCommittedState *parsers.CommittedStateEvent
+
+// 全局解析器注册表，main 初始化时装配内置解析器及用户自定义配置
+var registry = parsers.NewRegistry()
+
+// 全局指标计数器，main 初始化时根据环境变量配置
+var metrics = ingest.NewMetrics()
+
+// 全局存储后端，由 STORE_BACKEND 环境变量选择 MongoDB 或嵌入式 LevelDB
+var activeStore store.Store
+
+// 全局检查点存储，持久化每个被监听文件的读取位置，使
+// SKIP_HISTORICAL_LOGS=true 重启时不会重新跳到文件末尾而丢失数据。
+var checkpointStore *checkpoint.Store
+
+// 区块间隔分析相关的全局状态，由 runIngest 根据环境变量初始化
+var (
+	slowBlockThreshold float64
+	statsWindowSize    int
+	streakMinLength    int
+	statsWindow        *analytics.Window
+	streakTracker      *analytics.StreakTracker
+)
 
 func main() {
+	// 第一个参数选择运行模式："ingest"（默认）解析并写入日志，"trigger"
+	// 则在已写入的集合上打开 Change Stream 并向下游 Sink 转发事件。
+	mode := "ingest"
+	if len(os.Args) > 1 {
+		mode = os.Args[1]
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	installSignalHandler(cancel)
+
+	switch mode {
+	case "ingest":
+		runIngest(ctx)
+	case "trigger":
+		runTrigger(ctx)
+	default:
+		log.Fatalf("未知的运行模式 '%s'，可选值为 ingest 或 trigger", mode)
+	}
+}
+
+// installSignalHandler 监听 SIGINT/SIGTERM，收到信号后取消 cancel 对应的
+// context，使历史日志处理、文件监听等循环能够感知退出请求，排空在途的
+// 写入后再退出，而不是被直接杀死丢失数据。
+func installSignalHandler(cancel context.CancelFunc) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigCh
+		log.Printf("收到信号 %v，开始优雅退出...", sig)
+		cancel()
+	}()
+}
+
+// runIngest 是原有的日志解析与入库流程：读取历史日志、监听主日志文件，
+// 并通过所选的存储后端落库。ctx 在收到 SIGINT/SIGTERM 时被取消，驱动各
+// 处理循环优雅退出并保存检查点。
+func runIngest(ctx context.Context) {
 	// 从环境变量获取配置
-	mongoDBURI := getEnv("MONGO_URI", "mongodb://localhost:27017")
-	mongoDBDatabase := getEnv("MONGO_DATABASE", "node_logs")
 	logDir := getEnv("LOG_DIR", "./logs") // 从环境变量获取日志目录
 	mainLogName := getEnv("MAIN_LOG_NAME", "stdout-xx.txt")
 	skipHistorical := getEnv("SKIP_HISTORICAL_LOGS", "false") == "true"
+	parserConfigPath := getEnv("PARSER_CONFIG", "")
+	metricsAddr := getEnv("METRICS_ADDR", ":9090")
+	storeBackend := getEnv("STORE_BACKEND", "mongo")
+	slowBlockThreshold = getEnvFloat("SLOW_BLOCK_THRESHOLD", 5.0)
+	statsWindowSize = getEnvInt("STATS_WINDOW_SIZE", 100)
+	streakMinLength = getEnvInt("SLOW_BLOCK_STREAK_MIN", 2)
+	backfill := hasFlag("--backfill")
+
+	statsWindow = analytics.NewWindow(statsWindowSize)
+	streakTracker = analytics.NewStreakTracker(streakMinLength)
+
+	if parserConfigPath != "" {
+		if err := registry.RegisterConfig(parserConfigPath); err != nil {
+			log.Fatalf("加载解析器配置 '%s' 失败: %v", parserConfigPath, err)
+		}
+		log.Printf("已从 '%s' 加载自定义解析器", parserConfigPath)
+	}
 
-	log.Printf("数据库URI: %s", mongoDBURI)
-	log.Printf("数据库名: %s", mongoDBDatabase)
 	log.Printf("日志目录: %s", logDir)
 	log.Printf("跳过历史日志: %v", skipHistorical)
+	log.Printf("存储后端: %s", storeBackend)
+
+	switch storeBackend {
+	case "mongo":
+		activeStore = newMongoBackend()
+	case "leveldb":
+		activeStore = newLevelDBBackend()
+	default:
+		log.Fatalf("未知的 STORE_BACKEND '%s'，可选值为 mongo 或 leveldb", storeBackend)
+	}
+	defer activeStore.Close()
+
+	checkpointStore = checkpoint.New(activeStore)
+
+	// 在处理任何日志之前，确保各集合声明的唯一键约束已经就位
+	for _, p := range registry.Parsers() {
+		if p.KeyField() == "" {
+			continue
+		}
+		if err := activeStore.EnsureUnique(p.Collection(), p.KeyField()); err != nil {
+			log.Fatalf("为 %s 建立唯一键约束失败: %v", p.Collection(), err)
+		}
+	}
+	// block_time_gap 是由 committed_state 派生出的分析结果，并非某个
+	// 解析器的直接产物，因此单独确保其唯一键约束。
+	if err := activeStore.EnsureUnique("block_time_gap", "height"); err != nil {
+		log.Fatalf("为 block_time_gap 建立唯一键约束失败: %v", err)
+	}
+	if err := activeStore.EnsureUnique("block_time_stats", "height"); err != nil {
+		log.Fatalf("为 block_time_stats 建立唯一键约束失败: %v", err)
+	}
+	if err := activeStore.EnsureUnique("slow_block_streaks", "startHeight"); err != nil {
+		log.Fatalf("为 slow_block_streaks 建立唯一键约束失败: %v", err)
+	}
+
+	if backfill {
+		runBackfill()
+		return
+	}
+
+	metrics.Serve(metricsAddr)
+	log.Printf("指标服务已启动，监听地址: %s", metricsAddr)
+
+	mainLogFile := filepath.Join(logDir, mainLogName)
+
+	// 1. 处理历史日志文件（如果未设置跳过），再处理当前的主日志文件
+	if !skipHistorical {
+		processHistoricalLogs(ctx, logDir, mainLogName)
+		processSingleFile(ctx, mainLogFile, 0)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// 历史内容已经读到文件末尾，监听从当前末尾开始即可
+		watchLogFile(ctx, mainLogFile, -1)
+		return
+	}
+
+	// 跳过历史日志处理时，尝试从上次退出时保存的检查点恢复读取位置，
+	// 避免重启后直接跳到末尾、丢失关闭期间写入的数据。
+	offset := resumeOffset(mainLogFile)
+	switch {
+	case offset > 0:
+		log.Printf("跳过历史日志处理，从检查点恢复读取位置: offset=%d", offset)
+	case offset == 0:
+		log.Println("跳过历史日志处理，日志文件已轮换或检查点位于文件开头，从头开始监听")
+	default:
+		log.Println("跳过历史日志处理，未找到可用的检查点，从最新位置开始监听")
+	}
+	watchLogFile(ctx, mainLogFile, offset)
+}
+
+// newMongoBackend 连接 MongoDB 并用批量写入管道包装成一个 store.Store。
+func newMongoBackend() store.Store {
+	mongoDBURI := getEnv("MONGO_URI", "mongodb://localhost:27017")
+	mongoDBDatabase := getEnv("MONGO_DATABASE", "node_logs")
+	batchSize := getEnvInt("WRITE_BATCH_SIZE", 500)
+	flushInterval := getEnvDuration("WRITE_FLUSH_INTERVAL", time.Second)
+	writeWorkers := getEnvInt("WRITE_WORKERS", 4)
+
+	log.Printf("数据库URI: %s", mongoDBURI)
+	log.Printf("数据库名: %s", mongoDBDatabase)
 
-	// 连接到 MongoDB
 	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(mongoDBURI))
 	if err != nil {
 		log.Fatalf("无法连接到 MongoDB: %v", err)
 	}
-	defer client.Disconnect(context.Background())
-	err = client.Ping(context.Background(), nil)
-	if err != nil {
+	if err := client.Ping(context.Background(), nil); err != nil {
 		log.Fatalf("无法 Ping通 MongoDB: %v", err)
 	}
 	log.Println("成功连接到 MongoDB!")
+
 	db := client.Database(mongoDBDatabase)
+	writer := ingest.NewWriter(db, writeWorkers, batchSize, flushInterval, metrics)
+	writer.Start(writeWorkers)
 
-	// 在处理任何日志之前，确保索引存在
-	ensureIndexes(db)
+	return store.NewMongoStore(db, writer)
+}
 
-	// 1. 处理历史日志文件（如果未设置跳过）
-	if !skipHistorical {
-		processHistoricalLogs(logDir, mainLogName, db)
+// newLevelDBBackend 打开一个嵌入式 LevelDB 数据库作为 store.Store，
+// 供不想运行独立 MongoDB 实例的单机部署使用。
+func newLevelDBBackend() store.Store {
+	path := getEnv("LEVELDB_PATH", "./leveldb-data")
+	log.Printf("LevelDB 数据目录: %s", path)
+
+	s, err := store.NewLevelDBStore(path)
+	if err != nil {
+		log.Fatalf("打开 LevelDB 数据库 '%s' 失败: %v", path, err)
+	}
+	return s
+}
 
-		// 2. 处理当前的主日志文件
-		mainLogFile := filepath.Join(logDir, mainLogName)
-		processSingleFile(mainLogFile, db)
-	} else {
-		log.Println("跳过历史日志处理，直接开始监听最新日志")
+// runBackfill 按高度顺序重放 committed_state 中的全部历史记录，重新计算
+// block_time_stats 和 slow_block_streaks，而不是像正常运行那样只从日志
+// 尾部向前计算。
+func runBackfill() {
+	log.Println("开始执行 --backfill，重新计算区块间隔分析数据...")
+
+	events, err := loadCommittedStateInOrder(activeStore)
+	if err != nil {
+		log.Fatalf("加载 committed_state 失败: %v", err)
 	}
 
-	// 3. 实时监听主日志文件
-	mainLogFile := filepath.Join(logDir, mainLogName)
-	watchLogFile(mainLogFile, db)
+	statsWindow = analytics.NewWindow(statsWindowSize)
+	streakTracker = analytics.NewStreakTracker(streakMinLength)
+
+	var previous *parsers.CommittedStateEvent
+	for _, entry := range events {
+		if previous != nil {
+			timeDiff := entry.Timestamp.Sub(previous.Timestamp).Seconds()
+			recordBlockInterval(entry, previous.Height, timeDiff)
+		}
+		previous = entry
+	}
+	flushStreakTracker()
+
+	log.Printf("backfill 完成，共处理 %d 个区块", len(events))
+}
+
+// loadCommittedStateInOrder 按高度升序加载 committed_state 中的全部记录。
+// 不同存储后端的原生查询能力不同，因此按具体类型分别实现。
+func loadCommittedStateInOrder(s store.Store) ([]*parsers.CommittedStateEvent, error) {
+	switch backend := s.(type) {
+	case *store.MongoStore:
+		return loadCommittedStateFromMongo(backend.DB())
+	case *store.LevelDBStore:
+		return loadCommittedStateFromLevelDB(backend)
+	default:
+		return nil, fmt.Errorf("存储后端 %T 不支持 --backfill", s)
+	}
+}
+
+func loadCommittedStateFromMongo(db *mongo.Database) ([]*parsers.CommittedStateEvent, error) {
+	ctx := context.Background()
+	cursor, err := db.Collection("committed_state").Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"height": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []*parsers.CommittedStateEvent
+	for cursor.Next(ctx) {
+		entry := &parsers.CommittedStateEvent{}
+		if err := cursor.Decode(entry); err != nil {
+			return nil, err
+		}
+		events = append(events, entry)
+	}
+	return events, cursor.Err()
+}
+
+func loadCommittedStateFromLevelDB(s *store.LevelDBStore) ([]*parsers.CommittedStateEvent, error) {
+	it := s.Scan("committed_state", nil, nil)
+	defer it.Release()
+
+	var events []*parsers.CommittedStateEvent
+	for it.Next() {
+		entry := &parsers.CommittedStateEvent{}
+		if err := json.Unmarshal(it.Value(), entry); err != nil {
+			return nil, err
+		}
+		events = append(events, entry)
+	}
+	return events, it.Error()
+}
+
+// triggerCollections 是 trigger 模式下默认监听的集合：parseAndStore 写入
+// 的原始状态集合，以及由它派生出的区块间隔分析集合。
+var triggerCollections = []string{"committed_state", "block_time_gap"}
+
+// runTrigger 打开 MongoDB Change Stream 模式，把 triggerCollections 中新
+// 写入的文档重新发布给下游 Sink。目前实现了 stdout NDJSON、HTTP webhook
+// 和 NATS 主题三种 TRIGGER_SINK；Kafka 尚未实现，TRIGGER_SINK 设为 kafka
+// 会在启动时明确报错，而不是静默退化成其他行为。要求 MongoDB 以副本集
+// 模式运行，否则 Watch 会返回明确的错误。ctx 取消时 Change Stream 监听
+// 会随之退出。
+func runTrigger(ctx context.Context) {
+	mongoDBURI := getEnv("MONGO_URI", "mongodb://localhost:27017")
+	mongoDBDatabase := getEnv("MONGO_DATABASE", "node_logs")
+	sinkType := getEnv("TRIGGER_SINK", "stdout")
+	webhookURL := getEnv("TRIGGER_WEBHOOK_URL", "")
+	natsURL := getEnv("TRIGGER_NATS_URL", "")
+	natsSubject := getEnv("TRIGGER_NATS_SUBJECT", "")
+
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(mongoDBURI))
+	if err != nil {
+		log.Fatalf("无法连接到 MongoDB: %v", err)
+	}
+	defer client.Disconnect(context.Background())
+	db := client.Database(mongoDBDatabase)
+
+	var sink trigger.Sink
+	switch sinkType {
+	case "stdout":
+		sink = trigger.NewStdoutSink(os.Stdout)
+	case "webhook":
+		if webhookURL == "" {
+			log.Fatalf("TRIGGER_SINK=webhook 时必须设置 TRIGGER_WEBHOOK_URL")
+		}
+		sink = trigger.NewWebhookSink(webhookURL)
+	case "nats":
+		if natsURL == "" || natsSubject == "" {
+			log.Fatalf("TRIGGER_SINK=nats 时必须同时设置 TRIGGER_NATS_URL 和 TRIGGER_NATS_SUBJECT")
+		}
+		natsSink, err := trigger.NewNatsSink(natsURL, natsSubject)
+		if err != nil {
+			log.Fatalf("初始化 NATS sink 失败: %v", err)
+		}
+		defer natsSink.Close()
+		sink = natsSink
+	case "kafka":
+		log.Fatalf("TRIGGER_SINK=kafka 尚未实现，当前只支持 stdout、webhook 或 nats；trigger.Sink 接口已经为后续接入 Kafka 预留了扩展点")
+	default:
+		log.Fatalf("未知的 TRIGGER_SINK '%s'，可选值为 stdout、webhook 或 nats", sinkType)
+	}
+
+	log.Printf("以 trigger 模式启动，监听集合: %v，sink: %s", triggerCollections, sinkType)
+
+	watcher := trigger.NewWatcher(db, sink)
+	if err := watcher.WatchCollections(ctx, triggerCollections); err != nil {
+		log.Fatalf("变更流监听退出: %v", err)
+	}
 }
 
 // getEnv 获取环境变量，如果不存在则返回默认值
@@ -92,8 +378,61 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
-// processHistoricalLogs 查找并按顺序处理历史日志文件
-func processHistoricalLogs(logDir string, mainLogName string, db *mongo.Database) {
+// getEnvInt 获取整型环境变量，如果不存在或无法解析则返回默认值
+func getEnvInt(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("环境变量 %s 的值 '%s' 不是合法整数，使用默认值 %d", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// getEnvFloat 获取浮点型环境变量，如果不存在或无法解析则返回默认值
+func getEnvFloat(key string, fallback float64) float64 {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("环境变量 %s 的值 '%s' 不是合法浮点数，使用默认值 %g", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// hasFlag 判断命令行参数中是否包含给定的标志
+func hasFlag(flag string) bool {
+	for _, arg := range os.Args {
+		if arg == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// getEnvDuration 获取时间间隔环境变量，如果不存在或无法解析则返回默认值
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("环境变量 %s 的值 '%s' 不是合法时间间隔，使用默认值 %s", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// processHistoricalLogs 查找并按顺序处理历史日志文件。ctx 被取消时会在
+// 处理下一个文件之前提前退出。
+func processHistoricalLogs(ctx context.Context, logDir string, mainLogName string) {
 	log.Println("开始处理历史日志文件...")
 	files, err := os.ReadDir(logDir)
 	if err != nil {
@@ -118,15 +457,20 @@ func processHistoricalLogs(logDir string, mainLogName string, db *mongo.Database
 	})
 
 	for _, fileName := range historicalLogs {
+		if ctx.Err() != nil {
+			log.Println("收到退出信号，停止处理历史日志文件")
+			return
+		}
 		filePath := filepath.Join(logDir, fileName)
 		log.Printf("正在处理历史文件: %s", filePath)
-		processSingleFile(filePath, db)
+		processSingleFile(ctx, filePath, 0)
 	}
 	log.Println("历史日志文件处理完毕。")
 }
 
-// processSingleFile 读取并解析单个日志文件
-func processSingleFile(filePath string, db *mongo.Database) {
+// processSingleFile 从 startOffset 开始读取并解析单个日志文件，处理完毕
+// 或 ctx 被取消时都会保存一次检查点。
+func processSingleFile(ctx context.Context, filePath string, startOffset int64) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		if !os.IsNotExist(err) {
@@ -136,18 +480,42 @@ func processSingleFile(filePath string, db *mongo.Database) {
 	}
 	defer file.Close()
 
+	if startOffset > 0 {
+		if _, err := file.Seek(startOffset, 0); err != nil {
+			log.Printf("移动文件 '%s' 指针到 offset=%d 失败: %v", filePath, startOffset, err)
+		}
+	}
+
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		parseAndStore(scanner.Text(), db)
+		if ctx.Err() != nil {
+			log.Printf("收到退出信号，停止处理文件 '%s'", filePath)
+			break
+		}
+		parseAndStore(scanner.Text())
 	}
 
 	if err := scanner.Err(); err != nil {
 		log.Printf("读取日志文件 '%s' 时出错: %v", filePath, err)
 	}
+
+	if ctx.Err() != nil {
+		// 真正的退出信号：后面不会再有 watchLogFile 接力继续观测同一个
+		// streakTracker 了，必须在此把仍在进行中的慢区块区间交出去，
+		// 否则它会随进程一起消失，永远不会写入 slow_block_streaks。
+		flushStreakTracker()
+	}
+
+	if pos, err := file.Seek(0, os.SEEK_CUR); err == nil {
+		flushActiveStore()
+		saveCheckpoint(filePath, pos)
+	}
 }
 
-// watchLogFile 使用 fsnotify 实时监控文件变化
-func watchLogFile(filePath string, db *mongo.Database) {
+// watchLogFile 使用 fsnotify 实时监控文件变化。startOffset >= 0 时从该
+// 位置开始读取（用于重启后从检查点恢复）；为负数时沿用原有行为，从文件
+// 当前末尾开始监听。ctx 被取消时保存最后一次检查点后退出。
+func watchLogFile(ctx context.Context, filePath string, startOffset int64) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		log.Fatalf("创建文件监视器失败: %v", err)
@@ -171,9 +539,10 @@ func watchLogFile(filePath string, db *mongo.Database) {
 		}
 	}
 
-	// 初始打开文件并移到末尾，因为 processSingleFile 已经处理了现有内容
-	info, err := os.Stat(filePath)
-	if err == nil {
+	if startOffset >= 0 {
+		currentPos = startOffset
+	} else if info, err := os.Stat(filePath); err == nil {
+		// 初始打开文件并移到末尾，因为调用方已经处理了现有内容
 		currentPos = info.Size()
 	}
 	openAndSeek()
@@ -183,10 +552,20 @@ func watchLogFile(filePath string, db *mongo.Database) {
 		log.Fatalf("添加文件监视失败: %v", err)
 	}
 
-	log.Printf("开始实时监视文件: %s", filePath)
+	log.Printf("开始实时监视文件: %s (起始位置: %d)", filePath, currentPos)
 
 	for {
 		select {
+		case <-ctx.Done():
+			log.Println("收到退出信号，保存检查点并停止监视")
+			flushStreakTracker()
+			flushActiveStore()
+			saveCheckpoint(filePath, currentPos)
+			if file != nil {
+				file.Close()
+			}
+			return
+
 		case event, ok := <-watcher.Events:
 			if !ok {
 				return
@@ -203,6 +582,8 @@ func watchLogFile(filePath string, db *mongo.Database) {
 				}
 				currentPos = 0 // 新文件从头开始
 				openAndSeek()
+				flushActiveStore()
+				saveCheckpoint(filePath, currentPos)
 			}
 
 			if event.Op&fsnotify.Write == fsnotify.Write {
@@ -214,7 +595,7 @@ func watchLogFile(filePath string, db *mongo.Database) {
 				}
 				scanner := bufio.NewScanner(file)
 				for scanner.Scan() {
-					parseAndStore(scanner.Text(), db)
+					parseAndStore(scanner.Text())
 				}
 				if err := scanner.Err(); err != nil {
 					log.Printf("监视期间读取文件出错: %v", err)
@@ -224,6 +605,12 @@ func watchLogFile(filePath string, db *mongo.Database) {
 				if err == nil {
 					currentPos = pos
 				}
+				// 先阻塞等待这批数据真正落库（而不仅仅是进入写入队列），
+				// 再推进检查点，确保检查点记录的位置永远不会超前于已经
+				// 持久化的数据，否则进程崩溃会让队列里尚未落库的文档
+				// 连同它们对应的偏移量一起丢失。
+				flushActiveStore()
+				saveCheckpoint(filePath, currentPos)
 			}
 
 		case err, ok := <-watcher.Errors:
@@ -235,192 +622,251 @@ func watchLogFile(filePath string, db *mongo.Database) {
 	}
 }
 
-func parseAndStore(line string, db *mongo.Database) {
-	// 正则表达式保持不变
-	committedStateRegex := regexp.MustCompile(`I\[(.*?)\] Committed State\s+module=(.*?)\s+height=(.*?)\s+txs=(.*?)\s+appHash=(.*)`)
-	//allocateTokensRegex := regexp.MustCompile(`I\[(.*?)\] Allocate Tokens To Validator\s+module=(.*?)\s+validator=(.*?)\s+reward=(.*)`)
-	//executedBlockRegex := regexp.MustCompile(`I\[(.*?)\] Executed Block\s+module=(.*?)\s+height=(.*?)\s+validTxs=(.*?)\s+invalidTxs=(.*?)\s+hash=(.*)`)
+// fileIdentity 返回文件的 inode 号与当前大小，用于判断重启时磁盘上的文件
+// 是否还是检查点记录的那一个，而非日志轮换后的新文件。仅支持类 Unix
+// 系统。
+func fileIdentity(path string) (inode uint64, size int64, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, info.Size(), fmt.Errorf("无法获取文件 '%s' 的 inode 信息", path)
+	}
+	return stat.Ino, info.Size(), nil
+}
 
-	// 解析和存储逻辑保持不变
-	if matches := committedStateRegex.FindStringSubmatch(line); len(matches) > 0 {
-		timestamp, _ := time.Parse("2006-01-02|15:04:05.000", matches[1])
-		height, _ := strconv.ParseInt(matches[3], 10, 64)
-		txs, _ := strconv.Atoi(matches[4])
+// flushActiveStore 阻塞直到此前入队的事件全部落库，必须在每次调用
+// saveCheckpoint 之前执行，否则对于像 MongoStore 这样 Put 只是把文档
+// 排入异步写入队列的后端，检查点会在对应批次真正写入磁盘之前就被推进。
+func flushActiveStore() {
+	if activeStore == nil {
+		return
+	}
+	if err := activeStore.Flush(); err != nil {
+		log.Printf("刷新存储写入队列失败: %v", err)
+	}
+}
 
-		entry := CommittedState{
-			Timestamp: timestamp,
-			Module:    matches[2],
-			Height:    height,
-			Txs:       txs,
-			AppHash:   strings.TrimSpace(matches[5]),
-		}
+// saveCheckpoint 把 path 当前的读取位置连同文件 inode/size 一并持久化，
+// 供下次启动时判断能否从该位置继续读取。
+func saveCheckpoint(path string, offset int64) {
+	if checkpointStore == nil {
+		return
+	}
+	inode, size, err := fileIdentity(path)
+	if err != nil {
+		log.Printf("保存检查点前获取文件 '%s' 信息失败: %v", path, err)
+		return
+	}
+	cp := checkpoint.FileCheckpoint{Path: path, Inode: inode, Size: size, Offset: offset}
+	if err := checkpointStore.Save(cp); err != nil {
+		log.Printf("保存检查点 '%s' 失败: %v", path, err)
+	}
+}
 
-		// 存储 committed_state
-		collection := db.Collection("committed_state")
-		_, err := collection.InsertOne(context.Background(), entry)
-		if err != nil {
-			// 如果是重复键错误，则忽略，因为这意味着数据已经存在
-			if !mongo.IsDuplicateKeyError(err) {
-				log.Printf("写入 committed_state 到 MongoDB 时出错: %v", err)
-			}
-		}
+// resumeOffset 返回 path 应当恢复读取的位置：找到匹配 inode 的检查点时
+// 返回其记录的 offset；文件已轮换（inode 不一致）时返回 0 表示从头读取；
+// 没有可用的检查点时返回 -1，由调用方退回到默认行为（从文件末尾开始）。
+func resumeOffset(path string) int64 {
+	if checkpointStore == nil {
+		return -1
+	}
+	cp, found, err := checkpointStore.Load(path)
+	if err != nil {
+		log.Printf("加载文件 '%s' 的检查点失败: %v", path, err)
+		return -1
+	}
+	if !found {
+		return -1
+	}
 
-		// 检查与上一次提交的时间差
-		if lastCommittedState != nil {
-			timeDiff := entry.Timestamp.Sub(lastCommittedState.Timestamp).Seconds()
-
-			// 如果时间差大于等于5秒，记录到 block_time_gap 集合
-			if timeDiff >= 5.0 {
-				gapEntry := BlockTimeGap{
-					Timestamp:      entry.Timestamp,
-					Height:         entry.Height,
-					Txs:            entry.Txs,
-					TimeDiff:       timeDiff,
-					PreviousHeight: lastCommittedState.Height,
-				}
+	inode, _, err := fileIdentity(path)
+	if err != nil {
+		log.Printf("获取文件 '%s' 的 inode 信息失败: %v", path, err)
+		return -1
+	}
+	if inode != cp.Inode {
+		return 0
+	}
+	return cp.Offset
+}
 
-				gapCollection := db.Collection("block_time_gap")
-				_, err := gapCollection.InsertOne(context.Background(), gapEntry)
-				if err != nil {
-					if !mongo.IsDuplicateKeyError(err) {
-						log.Printf("写入 block_time_gap 到 MongoDB 时出错: %v", err)
-					}
-				} else {
-					log.Printf("检测到时间间隔 %.2f 秒 (区块 %d -> %d, 交易数: %d)",
-						timeDiff, lastCommittedState.Height, entry.Height, entry.Txs)
-				}
-			}
-		}
+// parseAndStore 将一行日志交给解析器注册表处理，并把匹配到的事件写入
+// 当前激活的存储后端（activeStore）。
+func parseAndStore(line string) {
+	metrics.AddLinesParsed(1)
 
-		// 更新最后一次提交的状态
-		lastCommittedState = &entry
+	event, ok := registry.Dispatch(line)
+	if !ok {
+		return
 	}
-}
 
-// ensureIndexes 创建 MongoDB 唯一索引以防止数据重复
-func ensureIndexes(db *mongo.Database) {
-	log.Println("正在确保 MongoDB 索引存在...")
+	if err := activeStore.Put(event.Collection(), eventKey(event), event); err != nil {
+		if err != store.ErrDuplicateKey {
+			log.Printf("写入 %s 时出错: %v", event.Collection(), err)
+		}
+	}
 
-	// 为 committed_state 创建唯一索引
-	committedStateCollection := db.Collection("committed_state")
-	err := createUniqueIndex(committedStateCollection, "height", "committed_state")
-	if err != nil {
-		log.Fatalf("为 committed_state 创建索引失败: %v", err)
+	// Committed State 事件额外驱动区块间隔分析，其它事件类型到此为止
+	entry, ok := event.(*parsers.CommittedStateEvent)
+	if !ok {
+		return
 	}
 
-	// 为 block_time_gap 创建唯一索引
-	blockTimeGapCollection := db.Collection("block_time_gap")
-	err = createUniqueIndex(blockTimeGapCollection, "height", "block_time_gap")
-	if err != nil {
-		log.Fatalf("为 block_time_gap 创建索引失败: %v", err)
+	if lastCommittedState != nil {
+		timeDiff := entry.Timestamp.Sub(lastCommittedState.Timestamp).Seconds()
+		recordBlockInterval(entry, lastCommittedState.Height, timeDiff)
 	}
 
-	log.Println("MongoDB 索引已准备就绪。")
+	// 更新最后一次提交的状态
+	lastCommittedState = entry
 }
 
-// createUniqueIndex 创建唯一索引，如果遇到重复键错误则先清理重复数据
-func createUniqueIndex(collection *mongo.Collection, fieldName string, collectionName string) error {
-	ctx := context.Background()
-
-	// 尝试创建索引
-	_, err := collection.Indexes().CreateOne(
-		ctx,
-		mongo.IndexModel{
-			Keys:    map[string]interface{}{fieldName: 1},
-			Options: options.Index().SetUnique(true),
-		},
-	)
+// recordBlockInterval 驱动区块间隔相关的三类分析产物：超过阈值时记录
+// 孤立的 block_time_gap 点位、滚动窗口的 block_time_stats 统计量，以及
+// 连续慢区块区间 slow_block_streaks。
+func recordBlockInterval(entry *parsers.CommittedStateEvent, previousHeight int64, timeDiff float64) {
+	aboveThreshold := timeDiff >= slowBlockThreshold
+
+	if aboveThreshold {
+		gapEntry := BlockTimeGap{
+			Timestamp:      entry.Timestamp,
+			Height:         entry.Height,
+			Txs:            entry.Txs,
+			TimeDiff:       timeDiff,
+			PreviousHeight: previousHeight,
+		}
+		if err := activeStore.Put("block_time_gap", store.HeightKey(entry.Height), gapEntry); err != nil && err != store.ErrDuplicateKey {
+			log.Printf("写入 block_time_gap 时出错: %v", err)
+		}
+		log.Printf("检测到时间间隔 %.2f 秒 (区块 %d -> %d, 交易数: %d)",
+			timeDiff, previousHeight, entry.Height, entry.Txs)
+	}
 
-	if err != nil {
-		// 检查是否是重复键错误
-		if strings.Contains(err.Error(), "E11000 duplicate key error") {
-			log.Printf("警告: 集合 %s 中存在重复的 %s 值，正在清理重复数据...", collectionName, fieldName)
-
-			// 删除重复数据，只保留每个 height 的第一条记录
-			err = removeDuplicates(collection, fieldName)
-			if err != nil {
-				return fmt.Errorf("清理重复数据失败: %v", err)
-			}
+	statsWindow.Add(timeDiff)
+	stats := statsWindow.Stats(entry.Height)
+	if err := upsertAnalytics("block_time_stats", store.HeightKey(entry.Height), stats); err != nil {
+		log.Printf("写入 block_time_stats 时出错: %v", err)
+	}
 
-			// 重新尝试创建索引
-			_, err = collection.Indexes().CreateOne(
-				ctx,
-				mongo.IndexModel{
-					Keys:    map[string]interface{}{fieldName: 1},
-					Options: options.Index().SetUnique(true),
-				},
-			)
-			if err != nil {
-				return fmt.Errorf("清理后创建索引仍然失败: %v", err)
-			}
-			log.Printf("成功为 %s 创建唯一索引", collectionName)
-		} else if strings.Contains(err.Error(), "IndexOptionsConflict") || strings.Contains(err.Error(), "already exists") {
-			// 索引已存在，这是正常的
-			log.Printf("索引已存在于 %s 集合", collectionName)
-		} else {
-			return err
-		}
-	} else {
-		log.Printf("成功为 %s 创建唯一索引", collectionName)
+	if streak := streakTracker.Observe(entry.Height, timeDiff, aboveThreshold); streak != nil {
+		saveStreak(streak)
 	}
+}
 
-	return nil
+// saveStreak 持久化一段已经结束的慢区块连续区间。
+func saveStreak(streak *analytics.Streak) {
+	if err := upsertAnalytics("slow_block_streaks", store.HeightKey(streak.StartHeight), streak); err != nil {
+		log.Printf("写入 slow_block_streaks 时出错: %v", err)
+	}
+	log.Printf("记录慢区块连续区间: %d -> %d，共 %d 个区块，累计耗时 %.2f 秒",
+		streak.StartHeight, streak.EndHeight, streak.Count, streak.TotalLostTime)
 }
 
-// removeDuplicates 删除集合中的重复数据，只保留每个字段值的第一条记录
-func removeDuplicates(collection *mongo.Collection, fieldName string) error {
-	ctx := context.Background()
+// flushStreakTracker 持久化仍在进行中、尚未因为一次低于阈值的间隔而结束
+// 的慢区块连续区间。必须在停止观测区块间隔之前调用（优雅退出、
+// --backfill 处理完所有区块），否则这段区间永远不会被写入
+// slow_block_streaks。
+func flushStreakTracker() {
+	if streakTracker == nil {
+		return
+	}
+	if streak := streakTracker.Finish(); streak != nil {
+		saveStreak(streak)
+	}
+}
 
-	// 使用聚合管道找出重复的记录
-	pipeline := []interface{}{
-		map[string]interface{}{
-			"$group": map[string]interface{}{
-				"_id":   "$" + fieldName,
-				"ids":   map[string]interface{}{"$push": "$_id"},
-				"count": map[string]interface{}{"$sum": 1},
-			},
-		},
-		map[string]interface{}{
-			"$match": map[string]interface{}{
-				"count": map[string]interface{}{"$gt": 1},
-			},
-		},
-	}
-
-	cursor, err := collection.Aggregate(ctx, pipeline)
-	if err != nil {
+// upsertAnalytics 写入按 key 去重的分析结果（block_time_stats、
+// slow_block_streaks），语义上是"替换为最新计算值"而不是"仅当不存在时
+// 插入"。activeStore.Put 的插入-忽略重复键语义是为原始事件摄入设计的，
+// 用在这里会导致 --backfill 重新计算时，已经有记录的高度被当成重复键
+// 静默丢弃、保留旧值，--backfill 也就起不到"重新计算整个分析集合"的
+// 作用。因此按具体后端类型分别走真正的 upsert 路径。
+func upsertAnalytics(collection string, key []byte, doc interface{}) error {
+	switch backend := activeStore.(type) {
+	case *store.MongoStore:
+		_, err := backend.DB().Collection(collection).ReplaceOne(
+			context.Background(),
+			bson.M{"_id": key},
+			doc,
+			options.Replace().SetUpsert(true),
+		)
 		return err
+	case *store.LevelDBStore:
+		return backend.Overwrite(collection, key, doc)
+	default:
+		return fmt.Errorf("存储后端 %T 不支持 upsertAnalytics", activeStore)
 	}
-	defer cursor.Close(ctx)
+}
 
-	var duplicateCount int
-	for cursor.Next(ctx) {
-		var result struct {
-			ID    interface{}   `bson:"_id"`
-			IDs   []interface{} `bson:"ids"`
-			Count int           `bson:"count"`
-		}
-		if err := cursor.Decode(&result); err != nil {
-			continue
-		}
+// eventKey 为事件推导出适合底层存储的原始字节键，统一基于
+// parsers.Event.Key() 而不是按具体类型重新判断，避免像
+// AllocateTokensEvent、配置化解析器产生的事件那样遗漏真实的去重键。
+// 只有明确以单一 height 字段标识的事件才编码为 store.HeightKey，使
+// LevelDB 后端可以按区块高度顺序扫描；Key() 返回空（目前仅限未声明
+// KeyField 的配置化解析器）时才退化为单调递增序号。
+func eventKey(event parsers.Event) []byte {
+	key := event.Key()
+	if len(key) == 0 {
+		return fallbackKey()
+	}
+	if height, ok := singleHeightKey(key); ok {
+		return store.HeightKey(height)
+	}
+	return canonicalKey(key)
+}
 
-		// 保留第一条，删除其余的
-		if len(result.IDs) > 1 {
-			idsToDelete := result.IDs[1:] // 跳过第一条
-			for _, id := range idsToDelete {
-				_, err := collection.DeleteOne(ctx, map[string]interface{}{"_id": id})
-				if err != nil {
-					log.Printf("删除重复记录失败: %v", err)
-				} else {
-					duplicateCount++
-				}
-			}
-		}
+// singleHeightKey 识别形如 bson.M{"height": n} 的单字段高度键。
+func singleHeightKey(key bson.M) (int64, bool) {
+	if len(key) != 1 {
+		return 0, false
 	}
+	v, ok := key["height"]
+	if !ok {
+		return 0, false
+	}
+	switch h := v.(type) {
+	case int64:
+		return h, true
+	case int:
+		return int64(h), true
+	default:
+		return 0, false
+	}
+}
 
-	if duplicateCount > 0 {
-		log.Printf("已清理 %d 条重复记录", duplicateCount)
+// canonicalKey 把一个 bson.M 键按字段名排序后拼接为确定性的字节串，
+// 使同样的键总能算出同样的字节表示。
+func canonicalKey(key bson.M) []byte {
+	names := make([]string, 0, len(key))
+	for name := range key {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s=%v", name, key[name]))
+	}
+	return []byte(strings.Join(parts, "&"))
+}
 
-	return nil
+var fallbackKeySeq uint64
+
+// fallbackKeyEpoch 是进程启动时刻，作为 fallbackKey 的前缀。
+var fallbackKeyEpoch = uint64(time.Now().UnixNano())
+
+// fallbackKey 为没有声明 KeyField、因而 Key() 返回空的配置化事件分配一
+// 个键：进程启动时刻加上进程内单调递增的序号。这类事件本就没有可去重
+// 的字段，每条都单独保存；该键在 MongoStore 中写作文档的 _id，在
+// LevelDB 中作为记录的键，启动时刻前缀使重启后分配的键不会与之前运行
+// 写入的键相同，因而既不会被当成重复键丢弃，也不会覆盖旧记录。
+func fallbackKey() []byte {
+	n := atomic.AddUint64(&fallbackKeySeq, 1)
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key[:8], fallbackKeyEpoch)
+	binary.BigEndian.PutUint64(key[8:], n)
+	return key
 }
@@ -0,0 +1,157 @@
+package parsers
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const timestampLayout = "2006-01-02|15:04:05.000"
+
+// CommittedStateEvent 对应 "Committed State" 日志行。
+type CommittedStateEvent struct {
+	Timestamp time.Time `bson:"timestamp"`
+	Module    string    `bson:"module"`
+	Height    int64     `bson:"height"`
+	Txs       int       `bson:"txs"`
+	AppHash   string    `bson:"appHash"`
+}
+
+func (e *CommittedStateEvent) Collection() string { return "committed_state" }
+func (e *CommittedStateEvent) Key() bson.M        { return bson.M{"height": e.Height} }
+
+// AllocateTokensEvent 对应 "Allocate Tokens To Validator" 日志行。
+type AllocateTokensEvent struct {
+	Timestamp time.Time `bson:"timestamp"`
+	Module    string    `bson:"module"`
+	Validator string    `bson:"validator"`
+	Reward    string    `bson:"reward"`
+}
+
+func (e *AllocateTokensEvent) Collection() string { return "allocate_tokens" }
+func (e *AllocateTokensEvent) Key() bson.M {
+	return bson.M{"timestamp": e.Timestamp, "validator": e.Validator}
+}
+
+// ExecutedBlockEvent 对应 "Executed Block" 日志行。
+type ExecutedBlockEvent struct {
+	Timestamp  time.Time `bson:"timestamp"`
+	Module     string    `bson:"module"`
+	Height     int64     `bson:"height"`
+	ValidTxs   int       `bson:"validTxs"`
+	InvalidTxs int       `bson:"invalidTxs"`
+	Hash       string    `bson:"hash"`
+}
+
+func (e *ExecutedBlockEvent) Collection() string { return "executed_block" }
+func (e *ExecutedBlockEvent) Key() bson.M        { return bson.M{"height": e.Height} }
+
+// committedStateParser 解析 "Committed State" 日志行。
+type committedStateParser struct {
+	re *regexp.Regexp
+}
+
+func newCommittedStateParser() *committedStateParser {
+	return &committedStateParser{
+		re: regexp.MustCompile(`I\[(.*?)\] Committed State\s+module=(.*?)\s+height=(.*?)\s+txs=(.*?)\s+appHash=(.*)`),
+	}
+}
+
+func (p *committedStateParser) Name() string       { return "committed_state" }
+func (p *committedStateParser) Collection() string { return "committed_state" }
+func (p *committedStateParser) KeyField() string   { return "height" }
+
+func (p *committedStateParser) Match(line string) (Event, bool) {
+	matches := p.re.FindStringSubmatch(line)
+	if matches == nil {
+		return nil, false
+	}
+	timestamp, _ := time.Parse(timestampLayout, matches[1])
+	height, _ := strconv.ParseInt(matches[3], 10, 64)
+	txs, _ := strconv.Atoi(matches[4])
+
+	return &CommittedStateEvent{
+		Timestamp: timestamp,
+		Module:    matches[2],
+		Height:    height,
+		Txs:       txs,
+		AppHash:   strings.TrimSpace(matches[5]),
+	}, true
+}
+
+// allocateTokensParser 解析 "Allocate Tokens To Validator" 日志行。
+type allocateTokensParser struct {
+	re *regexp.Regexp
+}
+
+func newAllocateTokensParser() *allocateTokensParser {
+	return &allocateTokensParser{
+		re: regexp.MustCompile(`I\[(.*?)\] Allocate Tokens To Validator\s+module=(.*?)\s+validator=(.*?)\s+reward=(.*)`),
+	}
+}
+
+func (p *allocateTokensParser) Name() string       { return "allocate_tokens" }
+func (p *allocateTokensParser) Collection() string { return "allocate_tokens" }
+func (p *allocateTokensParser) KeyField() string   { return "" }
+
+func (p *allocateTokensParser) Match(line string) (Event, bool) {
+	matches := p.re.FindStringSubmatch(line)
+	if matches == nil {
+		return nil, false
+	}
+	timestamp, _ := time.Parse(timestampLayout, matches[1])
+
+	return &AllocateTokensEvent{
+		Timestamp: timestamp,
+		Module:    matches[2],
+		Validator: strings.TrimSpace(matches[3]),
+		Reward:    strings.TrimSpace(matches[4]),
+	}, true
+}
+
+// executedBlockParser 解析 "Executed Block" 日志行。
+type executedBlockParser struct {
+	re *regexp.Regexp
+}
+
+func newExecutedBlockParser() *executedBlockParser {
+	return &executedBlockParser{
+		re: regexp.MustCompile(`I\[(.*?)\] Executed Block\s+module=(.*?)\s+height=(.*?)\s+validTxs=(.*?)\s+invalidTxs=(.*?)\s+hash=(.*)`),
+	}
+}
+
+func (p *executedBlockParser) Name() string       { return "executed_block" }
+func (p *executedBlockParser) Collection() string { return "executed_block" }
+func (p *executedBlockParser) KeyField() string   { return "height" }
+
+func (p *executedBlockParser) Match(line string) (Event, bool) {
+	matches := p.re.FindStringSubmatch(line)
+	if matches == nil {
+		return nil, false
+	}
+	timestamp, _ := time.Parse(timestampLayout, matches[1])
+	height, _ := strconv.ParseInt(matches[3], 10, 64)
+	validTxs, _ := strconv.Atoi(matches[4])
+	invalidTxs, _ := strconv.Atoi(matches[5])
+
+	return &ExecutedBlockEvent{
+		Timestamp:  timestamp,
+		Module:     matches[2],
+		Height:     height,
+		ValidTxs:   validTxs,
+		InvalidTxs: invalidTxs,
+		Hash:       strings.TrimSpace(matches[6]),
+	}, true
+}
+
+// BuiltinParsers 返回所有内置解析器。
+func BuiltinParsers() []Parser {
+	return []Parser{
+		newCommittedStateParser(),
+		newAllocateTokensParser(),
+		newExecutedBlockParser(),
+	}
+}
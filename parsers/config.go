@@ -0,0 +1,170 @@
+package parsers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"gopkg.in/yaml.v3"
+)
+
+// FieldType 描述配置文件中字段的目标类型。
+type FieldType string
+
+const (
+	FieldTypeString    FieldType = "string"
+	FieldTypeInt       FieldType = "int"
+	FieldTypeInt64     FieldType = "int64"
+	FieldTypeFloat     FieldType = "float"
+	FieldTypeTimestamp FieldType = "timestamp"
+)
+
+// FieldMapping 描述正则捕获组与目标字段之间的映射关系。
+type FieldMapping struct {
+	Name       string    `json:"name" yaml:"name"`
+	Group      int       `json:"group" yaml:"group"`
+	Type       FieldType `json:"type" yaml:"type"`
+	TimeLayout string    `json:"timeLayout,omitempty" yaml:"timeLayout,omitempty"`
+}
+
+// ParserConfig 描述一个由配置文件声明的自定义解析器。
+type ParserConfig struct {
+	Name       string         `json:"name" yaml:"name"`
+	Regex      string         `json:"regex" yaml:"regex"`
+	Collection string         `json:"collection" yaml:"collection"`
+	KeyField   string         `json:"keyField" yaml:"keyField"`
+	Fields     []FieldMapping `json:"fields" yaml:"fields"`
+}
+
+// Config 是 YAML/JSON 配置文件的顶层结构。
+type Config struct {
+	Parsers []ParserConfig `yaml:"parsers" json:"parsers"`
+}
+
+// LoadConfig 从 path 读取解析器配置，根据扩展名选择 YAML 或 JSON 解码。
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取解析器配置文件失败: %w", err)
+	}
+
+	cfg := &Config{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("解析 JSON 配置失败: %w", err)
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("解析 YAML 配置失败: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("不支持的配置文件扩展名: %s", filepath.Ext(path))
+	}
+
+	return cfg, nil
+}
+
+// configuredEvent 是由配置文件生成的通用事件，以 bson.M 承载任意字段。
+type configuredEvent struct {
+	collection string
+	keyField   string
+	fields     bson.M
+}
+
+func (e *configuredEvent) Collection() string { return e.collection }
+
+func (e *configuredEvent) Key() bson.M {
+	if e.keyField == "" {
+		return bson.M{}
+	}
+	return bson.M{e.keyField: e.fields[e.keyField]}
+}
+
+// MarshalBSON 使生成的事件可以直接作为文档插入 MongoDB。
+func (e *configuredEvent) MarshalBSON() ([]byte, error) {
+	return bson.Marshal(e.fields)
+}
+
+// MarshalJSON 使生成的事件可以直接写入 LevelDB 后端。e.fields 是未导出
+// 字段，不加这个方法的话 encoding/json 会因为看不到任何可导出字段而把
+// 整个事件序列化成 "{}"，数据就这样安静地丢了。
+func (e *configuredEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.fields)
+}
+
+// configuredParser 是根据 ParserConfig 构建的动态解析器。
+type configuredParser struct {
+	cfg ParserConfig
+	re  *regexp.Regexp
+}
+
+// NewConfiguredParsers 将配置文件中的每一项编译为可用的 Parser。
+func NewConfiguredParsers(cfg *Config) ([]Parser, error) {
+	parsers := make([]Parser, 0, len(cfg.Parsers))
+	for _, pc := range cfg.Parsers {
+		re, err := regexp.Compile(pc.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("解析器 %q 的正则表达式无效: %w", pc.Name, err)
+		}
+		parsers = append(parsers, &configuredParser{cfg: pc, re: re})
+	}
+	return parsers, nil
+}
+
+func (p *configuredParser) Name() string       { return p.cfg.Name }
+func (p *configuredParser) Collection() string { return p.cfg.Collection }
+func (p *configuredParser) KeyField() string   { return p.cfg.KeyField }
+
+func (p *configuredParser) Match(line string) (Event, bool) {
+	matches := p.re.FindStringSubmatch(line)
+	if matches == nil {
+		return nil, false
+	}
+
+	fields := bson.M{}
+	for _, fm := range p.cfg.Fields {
+		if fm.Group <= 0 || fm.Group >= len(matches) {
+			continue
+		}
+		raw := strings.TrimSpace(matches[fm.Group])
+		value, err := convertField(raw, fm)
+		if err != nil {
+			continue
+		}
+		fields[fm.Name] = value
+	}
+
+	return &configuredEvent{
+		collection: p.cfg.Collection,
+		keyField:   p.cfg.KeyField,
+		fields:     fields,
+	}, true
+}
+
+func convertField(raw string, fm FieldMapping) (interface{}, error) {
+	switch fm.Type {
+	case FieldTypeInt:
+		return strconv.Atoi(raw)
+	case FieldTypeInt64:
+		return strconv.ParseInt(raw, 10, 64)
+	case FieldTypeFloat:
+		return strconv.ParseFloat(raw, 64)
+	case FieldTypeTimestamp:
+		layout := fm.TimeLayout
+		if layout == "" {
+			layout = timestampLayout
+		}
+		return time.Parse(layout, raw)
+	case FieldTypeString, "":
+		return raw, nil
+	default:
+		return nil, fmt.Errorf("未知的字段类型: %s", fm.Type)
+	}
+}
@@ -0,0 +1,153 @@
+package parsers
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConvertField(t *testing.T) {
+	cases := []struct {
+		name    string
+		raw     string
+		fm      FieldMapping
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "int", raw: "42", fm: FieldMapping{Type: FieldTypeInt}, want: 42},
+		{name: "int64", raw: "42", fm: FieldMapping{Type: FieldTypeInt64}, want: int64(42)},
+		{name: "float", raw: "3.5", fm: FieldMapping{Type: FieldTypeFloat}, want: 3.5},
+		{name: "string", raw: "hello", fm: FieldMapping{Type: FieldTypeString}, want: "hello"},
+		{name: "default to string", raw: "hello", fm: FieldMapping{Type: ""}, want: "hello"},
+		{name: "invalid int", raw: "abc", fm: FieldMapping{Type: FieldTypeInt}, wantErr: true},
+		{name: "unknown type", raw: "abc", fm: FieldMapping{Type: "bogus"}, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := convertField(c.raw, c.fm)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error converting %q as %s", c.raw, c.fm.Type)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != c.want {
+				t.Fatalf("expected %v (%T), got %v (%T)", c.want, c.want, got, got)
+			}
+		})
+	}
+}
+
+func TestConvertFieldTimestamp(t *testing.T) {
+	fm := FieldMapping{Type: FieldTypeTimestamp, TimeLayout: "2006-01-02"}
+	got, err := convertField("2024-03-05", fm)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ts, ok := got.(interface{ Format(string) string })
+	if !ok {
+		t.Fatalf("expected a time.Time, got %T", got)
+	}
+	if formatted := ts.Format("2006-01-02"); formatted != "2024-03-05" {
+		t.Fatalf("expected 2024-03-05, got %s", formatted)
+	}
+}
+
+func TestNewConfiguredParsersRejectsInvalidRegex(t *testing.T) {
+	cfg := &Config{Parsers: []ParserConfig{{Name: "broken", Regex: "("}}}
+	if _, err := NewConfiguredParsers(cfg); err == nil {
+		t.Fatal("expected an error for an invalid regex")
+	}
+}
+
+func TestConfiguredParserMatchAndKey(t *testing.T) {
+	cfg := &Config{
+		Parsers: []ParserConfig{
+			{
+				Name:       "custom_event",
+				Regex:      `I\[(.*?)\] Custom Event\s+id=(.*?)\s+amount=(.*)`,
+				Collection: "custom_event",
+				KeyField:   "id",
+				Fields: []FieldMapping{
+					{Name: "id", Group: 2, Type: FieldTypeString},
+					{Name: "amount", Group: 3, Type: FieldTypeFloat},
+				},
+			},
+		},
+	}
+
+	ps, err := NewConfiguredParsers(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ps) != 1 {
+		t.Fatalf("expected 1 parser, got %d", len(ps))
+	}
+	p := ps[0]
+
+	if p.Collection() != "custom_event" || p.KeyField() != "id" {
+		t.Fatalf("unexpected parser metadata: collection=%s keyField=%s", p.Collection(), p.KeyField())
+	}
+
+	event, ok := p.Match("I[2024-01-01|00:00:00.000] Custom Event id=abc123 amount=9.5")
+	if !ok {
+		t.Fatal("expected the line to match")
+	}
+	if event.Collection() != "custom_event" {
+		t.Fatalf("expected collection custom_event, got %s", event.Collection())
+	}
+
+	key := event.Key()
+	if len(key) != 1 || key["id"] != "abc123" {
+		t.Fatalf("expected Key()={id: abc123}, got %v", key)
+	}
+
+	// configuredEvent 只有未导出字段，encoding/json 必须走自定义的
+	// MarshalJSON 才能看到 e.fields，否则 LevelDB 后端会把整个文档存成
+	// 字面量 "{}"。
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("unexpected error marshaling event to JSON: %v", err)
+	}
+	if string(data) == "{}" {
+		t.Fatal("expected JSON marshaling to include the event's fields, got empty object \"{}\"")
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error decoding marshaled JSON: %v", err)
+	}
+	if decoded["id"] != "abc123" || decoded["amount"] != 9.5 {
+		t.Fatalf("expected marshaled JSON to contain id/amount fields, got %v", decoded)
+	}
+}
+
+func TestConfiguredParserWithoutKeyFieldHasEmptyKey(t *testing.T) {
+	cfg := &Config{
+		Parsers: []ParserConfig{
+			{
+				Name:       "no_key",
+				Regex:      `I\[(.*?)\] No Key\s+value=(.*)`,
+				Collection: "no_key",
+				Fields: []FieldMapping{
+					{Name: "value", Group: 2, Type: FieldTypeString},
+				},
+			},
+		},
+	}
+
+	ps, err := NewConfiguredParsers(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	event, ok := ps[0].Match("I[2024-01-01|00:00:00.000] No Key value=foo")
+	if !ok {
+		t.Fatal("expected the line to match")
+	}
+	if key := event.Key(); len(key) != 0 {
+		t.Fatalf("expected an empty key when KeyField is unset, got %v", key)
+	}
+}
@@ -0,0 +1,26 @@
+// Package parsers 提供可插拔的日志行解析器，将原始日志文本转换为
+// 结构化的 Event，并由上层调度器写入对应的存储集合。
+package parsers
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// Event 表示一条被解析器成功匹配的日志事件。
+type Event interface {
+	// Collection 返回该事件应当写入的 MongoDB 集合名。
+	Collection() string
+	// Key 返回用于去重/建唯一索引的查询条件。
+	Key() bson.M
+}
+
+// Parser 定义了一个可插拔的日志行解析器。内置解析器和由配置文件
+// 生成的动态解析器都实现该接口。
+type Parser interface {
+	// Name 返回解析器名称，主要用于日志与索引管理。
+	Name() string
+	// Match 尝试解析一行日志，成功时返回对应的 Event。
+	Match(line string) (Event, bool)
+	// Collection 返回该解析器对应的目标集合名。
+	Collection() string
+	// KeyField 返回该集合用于去重的唯一索引字段名。
+	KeyField() string
+}
@@ -0,0 +1,48 @@
+package parsers
+
+// Registry 按注册顺序持有全部解析器，并将每一行日志分发给第一个
+// 匹配成功的解析器。
+type Registry struct {
+	parsers []Parser
+}
+
+// NewRegistry 创建一个仅包含内置解析器的 Registry。
+func NewRegistry() *Registry {
+	return &Registry{parsers: BuiltinParsers()}
+}
+
+// Register 追加一个解析器，通常用于加载用户自定义配置。
+func (r *Registry) Register(p Parser) {
+	r.parsers = append(r.parsers, p)
+}
+
+// RegisterConfig 从配置文件加载自定义解析器并追加到 Registry。
+func (r *Registry) RegisterConfig(path string) error {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	custom, err := NewConfiguredParsers(cfg)
+	if err != nil {
+		return err
+	}
+	for _, p := range custom {
+		r.Register(p)
+	}
+	return nil
+}
+
+// Parsers 返回当前注册的全部解析器，供调用方枚举集合/索引信息。
+func (r *Registry) Parsers() []Parser {
+	return r.parsers
+}
+
+// Dispatch 依次尝试每个解析器，返回第一个匹配的 Event。
+func (r *Registry) Dispatch(line string) (Event, bool) {
+	for _, p := range r.parsers {
+		if event, ok := p.Match(line); ok {
+			return event, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,11 @@
+package store
+
+import "encoding/binary"
+
+// HeightKey 将区块高度编码为大端字节序，使按高度范围扫描时键的字典序与
+// 数值顺序一致。
+func HeightKey(height int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(height))
+	return key
+}
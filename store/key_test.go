@@ -0,0 +1,48 @@
+package store
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHeightKeyPreservesNumericOrder(t *testing.T) {
+	heights := []int64{0, 1, 2, 255, 256, 1 << 40}
+	for i := 1; i < len(heights); i++ {
+		prev := HeightKey(heights[i-1])
+		cur := HeightKey(heights[i])
+		if bytes.Compare(prev, cur) >= 0 {
+			t.Fatalf("expected HeightKey(%d) < HeightKey(%d) lexicographically, got %v >= %v",
+				heights[i-1], heights[i], prev, cur)
+		}
+	}
+}
+
+func TestHeightKeyLength(t *testing.T) {
+	if len(HeightKey(42)) != 8 {
+		t.Fatalf("expected an 8-byte big-endian key, got %d bytes", len(HeightKey(42)))
+	}
+}
+
+func TestCollectionKeyStrippingPrefixRecoversKey(t *testing.T) {
+	key := HeightKey(7)
+	dbKey := collectionKey("committed_state", key)
+
+	wantPrefix := append([]byte("committed_state"), 0x00)
+	if !bytes.HasPrefix(dbKey, wantPrefix) {
+		t.Fatalf("expected dbKey to start with collection prefix %v, got %v", wantPrefix, dbKey)
+	}
+
+	stripped := dbKey[len(wantPrefix):]
+	if !bytes.Equal(stripped, key) {
+		t.Fatalf("expected stripping the collection prefix to recover the original key %v, got %v", key, stripped)
+	}
+}
+
+func TestCollectionKeyDistinguishesCollections(t *testing.T) {
+	key := HeightKey(1)
+	a := collectionKey("block_time_stats", key)
+	b := collectionKey("slow_block_streaks", key)
+	if bytes.Equal(a, b) {
+		t.Fatal("expected different collections with the same key to produce different dbKeys")
+	}
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// LevelDBStore 是基于嵌入式 github.com/syndtr/goleveldb 的 Store 实现，
+// 用于不想运行独立 MongoDB 实例的单机部署场景。committed_state 等按高度
+// 建键的集合使用 HeightKey 编码，使范围扫描落在顺序的键区间上。
+type LevelDBStore struct {
+	db *leveldb.DB
+
+	mu        sync.Mutex
+	uniqueSet map[string]bool // collection -> 是否要求唯一键
+}
+
+// NewLevelDBStore 打开（或创建）path 下的 LevelDB 数据库。
+func NewLevelDBStore(path string) (*LevelDBStore, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &LevelDBStore{db: db, uniqueSet: make(map[string]bool)}, nil
+}
+
+func collectionKey(collection string, key []byte) []byte {
+	prefix := append([]byte(collection), 0x00)
+	return append(prefix, key...)
+}
+
+// Put 将 doc 序列化为 JSON 并写入 collection 下的 key。如果该集合通过
+// EnsureUnique 声明了唯一键约束，且 key 已存在，则返回 ErrDuplicateKey
+// 而不覆盖已有数据。
+func (s *LevelDBStore) Put(collection string, key []byte, doc interface{}) error {
+	s.mu.Lock()
+	requireUnique := s.uniqueSet[collection]
+	s.mu.Unlock()
+
+	dbKey := collectionKey(collection, key)
+
+	if requireUnique {
+		exists, err := s.db.Has(dbKey, nil)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrDuplicateKey
+		}
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return s.db.Put(dbKey, data, nil)
+}
+
+// Has 判断 collection 下是否存在 key。
+func (s *LevelDBStore) Has(collection string, key []byte) (bool, error) {
+	return s.db.Has(collectionKey(collection, key), nil)
+}
+
+// Overwrite 无条件写入 doc，跳过 Put 对 EnsureUnique 声明的唯一键做的重复
+// 检查。用于 block_time_stats/slow_block_streaks 这类需要按 key 重新计算
+// 并替换旧值的场景（例如 --backfill），这类写入语义上是"更新"而不是
+// "插入"，不应该被当成重复键静默丢弃。
+func (s *LevelDBStore) Overwrite(collection string, key []byte, doc interface{}) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return s.db.Put(collectionKey(collection, key), data, nil)
+}
+
+// Flush 是 Put 之外的空操作：LevelDBStore 的写入本身就是同步的，没有
+// 额外需要等待落库的缓冲区。
+func (s *LevelDBStore) Flush() error {
+	return nil
+}
+
+// Get 读取 collection 下 key 对应的原始字节，ok 为 false 表示不存在。这是
+// Store 接口之外的能力，供需要直接取值（而非范围扫描）的调用方（例如
+// checkpoint 包）通过类型断言使用。
+func (s *LevelDBStore) Get(collection string, key []byte) (data []byte, ok bool, err error) {
+	data, err = s.db.Get(collectionKey(collection, key), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Scan 返回 collection 下 [from, to) 范围内的迭代器，按键的字典序（对
+// HeightKey 而言即按高度数值）升序遍历。
+func (s *LevelDBStore) Scan(collection string, from, to []byte) Iterator {
+	var r *util.Range
+	if from == nil && to == nil {
+		r = util.BytesPrefix(append([]byte(collection), 0x00))
+	} else {
+		r = &util.Range{
+			Start: collectionKey(collection, from),
+			Limit: collectionKey(collection, to),
+		}
+	}
+	return &levelDBIterator{it: s.db.NewIterator(r, nil), prefixLen: len(collection) + 1}
+}
+
+// EnsureUnique 记录 collection 要求唯一键，后续的 Put 会在写入前先做一次
+// Has 检查——这就是 LevelDB 场景下"唯一索引"的全部含义。field 参数只用于
+// 和 MongoDB 实现保持一致的签名，LevelDB 的唯一性始终针对 Put 传入的 key。
+func (s *LevelDBStore) EnsureUnique(collection string, field string) error {
+	s.mu.Lock()
+	s.uniqueSet[collection] = true
+	s.mu.Unlock()
+	return nil
+}
+
+// Close 关闭底层的 LevelDB 句柄。
+func (s *LevelDBStore) Close() error {
+	return s.db.Close()
+}
+
+// levelDBIterator 适配 goleveldb 的迭代器，剥离内部使用的集合前缀。
+type levelDBIterator struct {
+	it        iterator
+	prefixLen int
+}
+
+// iterator 是 goleveldb 的 DB.NewIterator 所返回迭代器用到的最小方法集合，便于测试替换。
+type iterator interface {
+	Next() bool
+	Key() []byte
+	Value() []byte
+	Release()
+	Error() error
+}
+
+func (i *levelDBIterator) Next() bool { return i.it.Next() }
+
+func (i *levelDBIterator) Key() []byte {
+	key := i.it.Key()
+	if len(key) < i.prefixLen {
+		return nil
+	}
+	stripped := make([]byte, len(key)-i.prefixLen)
+	copy(stripped, key[i.prefixLen:])
+	return stripped
+}
+
+func (i *levelDBIterator) Value() []byte {
+	value := i.it.Value()
+	copied := make([]byte, len(value))
+	copy(copied, value)
+	return copied
+}
+
+func (i *levelDBIterator) Release()     { i.it.Release() }
+func (i *levelDBIterator) Error() error { return i.it.Error() }
@@ -0,0 +1,190 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/xueqianLu/NodeLogInsight/ingest"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore 是基于 MongoDB 的 Store 实现。写入经由 ingest.Writer 的批量
+// 写入管道完成，Has/EnsureUnique 等需要强一致性的操作直接查询 MongoDB。
+type MongoStore struct {
+	db     *mongo.Database
+	writer *ingest.Writer
+}
+
+// NewMongoStore 创建一个以 writer 承载批量写入的 MongoStore。
+func NewMongoStore(db *mongo.Database, writer *ingest.Writer) *MongoStore {
+	return &MongoStore{db: db, writer: writer}
+}
+
+// DB 返回底层的 *mongo.Database，供需要原生查询能力的调用方（例如
+// analytics 的 --backfill）直接访问。
+func (s *MongoStore) DB() *mongo.Database {
+	return s.db
+}
+
+// Put 将 doc 排入批量写入管道，并把 key 写作文档的 _id，使 Has 能够按 key
+// 查到对应记录。doc 先经过 bson 编解码转换为 bson.M，以便兼容自定义
+// MarshalBSON 的类型（例如 parsers.configuredEvent）。
+func (s *MongoStore) Put(collection string, key []byte, doc interface{}) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	var m bson.M
+	if err := bson.Unmarshal(raw, &m); err != nil {
+		return err
+	}
+	m["_id"] = key
+	s.writer.Enqueue(collection, m)
+	return nil
+}
+
+// Has 查询 collection 中是否存在 _id 等于 key 的文档。
+func (s *MongoStore) Has(collection string, key []byte) (bool, error) {
+	count, err := s.db.Collection(collection).CountDocuments(context.Background(), bson.M{"_id": key})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Scan 在 MongoStore 上不受支持：范围扫描属于 LevelDB 后端的能力，
+// MongoDB 场景下应直接使用其原生查询接口。
+func (s *MongoStore) Scan(collection string, from, to []byte) Iterator {
+	return errIterator{err: fmt.Errorf("MongoStore 不支持 Scan，请直接使用 MongoDB 查询")}
+}
+
+// EnsureUnique 为 collection.field 创建唯一索引，如果已有数据违反该约束
+// 则先清理重复数据。
+func (s *MongoStore) EnsureUnique(collection string, field string) error {
+	return createUniqueIndex(s.db.Collection(collection), field, collection)
+}
+
+// Flush 阻塞直到写入管道中此前入队的文档全部完成 BulkWrite。
+func (s *MongoStore) Flush() error {
+	s.writer.Flush()
+	return nil
+}
+
+// Close 刷新批量写入管道中尚未落库的文档，然后断开底层的 MongoDB 连接。
+func (s *MongoStore) Close() error {
+	s.writer.Close()
+	return s.db.Client().Disconnect(context.Background())
+}
+
+// errIterator 是一个始终返回固定错误的空迭代器。
+type errIterator struct{ err error }
+
+func (errIterator) Next() bool     { return false }
+func (errIterator) Key() []byte    { return nil }
+func (errIterator) Value() []byte  { return nil }
+func (errIterator) Release()       {}
+func (i errIterator) Error() error { return i.err }
+
+// createUniqueIndex 创建唯一索引，如果遇到重复键错误则先清理重复数据
+func createUniqueIndex(collection *mongo.Collection, fieldName string, collectionName string) error {
+	ctx := context.Background()
+
+	_, err := collection.Indexes().CreateOne(
+		ctx,
+		mongo.IndexModel{
+			Keys:    map[string]interface{}{fieldName: 1},
+			Options: options.Index().SetUnique(true),
+		},
+	)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "E11000 duplicate key error") {
+			log.Printf("警告: 集合 %s 中存在重复的 %s 值，正在清理重复数据...", collectionName, fieldName)
+
+			err = removeDuplicates(collection, fieldName)
+			if err != nil {
+				return fmt.Errorf("清理重复数据失败: %v", err)
+			}
+
+			_, err = collection.Indexes().CreateOne(
+				ctx,
+				mongo.IndexModel{
+					Keys:    map[string]interface{}{fieldName: 1},
+					Options: options.Index().SetUnique(true),
+				},
+			)
+			if err != nil {
+				return fmt.Errorf("清理后创建索引仍然失败: %v", err)
+			}
+			log.Printf("成功为 %s 创建唯一索引", collectionName)
+		} else if strings.Contains(err.Error(), "IndexOptionsConflict") || strings.Contains(err.Error(), "already exists") {
+			log.Printf("索引已存在于 %s 集合", collectionName)
+		} else {
+			return err
+		}
+	} else {
+		log.Printf("成功为 %s 创建唯一索引", collectionName)
+	}
+
+	return nil
+}
+
+// removeDuplicates 删除集合中的重复数据，只保留每个字段值的第一条记录
+func removeDuplicates(collection *mongo.Collection, fieldName string) error {
+	ctx := context.Background()
+
+	pipeline := []interface{}{
+		map[string]interface{}{
+			"$group": map[string]interface{}{
+				"_id":   "$" + fieldName,
+				"ids":   map[string]interface{}{"$push": "$_id"},
+				"count": map[string]interface{}{"$sum": 1},
+			},
+		},
+		map[string]interface{}{
+			"$match": map[string]interface{}{
+				"count": map[string]interface{}{"$gt": 1},
+			},
+		},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var duplicateCount int
+	for cursor.Next(ctx) {
+		var result struct {
+			ID    interface{}   `bson:"_id"`
+			IDs   []interface{} `bson:"ids"`
+			Count int           `bson:"count"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			continue
+		}
+
+		if len(result.IDs) > 1 {
+			idsToDelete := result.IDs[1:]
+			for _, id := range idsToDelete {
+				_, err := collection.DeleteOne(ctx, map[string]interface{}{"_id": id})
+				if err != nil {
+					log.Printf("删除重复记录失败: %v", err)
+				} else {
+					duplicateCount++
+				}
+			}
+		}
+	}
+
+	if duplicateCount > 0 {
+		log.Printf("已清理 %d 条重复记录", duplicateCount)
+	}
+
+	return nil
+}
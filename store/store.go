@@ -0,0 +1,41 @@
+// Package store 抽象了事件的持久化方式，使上层解析/调度逻辑不依赖某一
+// 具体数据库。目前提供 MongoDB 和嵌入式 LevelDB 两种实现，由
+// STORE_BACKEND 环境变量选择。
+package store
+
+import "errors"
+
+// ErrDuplicateKey 在 Put 发现 key 已存在且该集合要求唯一键时返回，调用方
+// 可以像处理 MongoDB 的重复键错误一样安全地忽略它。
+var ErrDuplicateKey = errors.New("store: duplicate key")
+
+// Iterator 遍历 Scan 返回的一段有序键值对。
+type Iterator interface {
+	// Next 移动到下一条记录，没有更多记录时返回 false。
+	Next() bool
+	Key() []byte
+	Value() []byte
+	// Release 释放迭代器持有的底层资源，使用完毕后必须调用。
+	Release()
+	// Error 返回迭代过程中遇到的错误（如果有）。
+	Error() error
+}
+
+// Store 抽象了事件的持久化方式。
+type Store interface {
+	// Put 写入一条文档。collection 标识逻辑上的数据分组（MongoDB 集合名
+	// 或 LevelDB 的键前缀），key 是该文档的唯一键原始字节表示。
+	Put(collection string, key []byte, doc interface{}) error
+	// Has 判断给定 key 是否已经存在。
+	Has(collection string, key []byte) (bool, error)
+	// Scan 返回 [from, to) 范围内按 key 排序的迭代器。
+	Scan(collection string, from, to []byte) Iterator
+	// EnsureUnique 确保 collection 中 field 字段的唯一性约束已经就位。
+	EnsureUnique(collection string, field string) error
+	// Flush 阻塞直到此前所有 Put 调用对应的写入都已经落到持久化存储，而
+	// 不只是进入内存中的写入队列。在推进任何标记"这些数据已安全落库"的
+	// 外部状态（例如文件读取检查点）之前必须先调用 Flush。
+	Flush() error
+	// Close 释放底层连接/句柄。
+	Close() error
+}
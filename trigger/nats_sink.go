@@ -0,0 +1,35 @@
+package trigger
+
+import (
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NatsSink 将每条变更事件发布到 NATS 的一个主题（subject）上。
+type NatsSink struct {
+	subject string
+	conn    *nats.Conn
+}
+
+// NewNatsSink 连接到 url 指定的 NATS 服务器，并创建一个发布到 subject 的
+// NatsSink。调用方负责在不再使用时调用 Close 释放连接。
+func NewNatsSink(url, subject string) (*NatsSink, error) {
+	conn, err := nats.Connect(url)
+	if err != nil {
+		return nil, fmt.Errorf("连接 NATS 服务器失败: %w", err)
+	}
+	return &NatsSink{subject: subject, conn: conn}, nil
+}
+
+func (s *NatsSink) Send(event []byte) error {
+	if err := s.conn.Publish(s.subject, event); err != nil {
+		return fmt.Errorf("发布到 NATS 主题 '%s' 失败: %w", s.subject, err)
+	}
+	return nil
+}
+
+// Close 关闭底层的 NATS 连接。
+func (s *NatsSink) Close() {
+	s.conn.Close()
+}
@@ -0,0 +1,12 @@
+// Package trigger 实现了变更流触发模式：监听 MongoDB Change Stream
+// 上的写入事件，并将其重新发布到一个可插拔的输出目标（Sink）。
+package trigger
+
+// Sink 是变更事件的可插拔输出目标。目前提供 stdout NDJSON
+// （StdoutSink）、HTTP webhook（WebhookSink）和 NATS 主题（NatsSink）三种
+// 实现；Kafka 尚未实现，只需实现该接口、再在启动时注册给 Watcher 即可
+// 接入，无需改动调度逻辑。
+type Sink interface {
+	// Send 发送一条已序列化为 JSON 的变更事件。
+	Send(event []byte) error
+}
@@ -0,0 +1,21 @@
+package trigger
+
+import (
+	"fmt"
+	"io"
+)
+
+// StdoutSink 将每条变更事件以 NDJSON（每行一个 JSON 对象）的形式写出。
+type StdoutSink struct {
+	out io.Writer
+}
+
+// NewStdoutSink 创建一个写入 w 的 StdoutSink。
+func NewStdoutSink(w io.Writer) *StdoutSink {
+	return &StdoutSink{out: w}
+}
+
+func (s *StdoutSink) Send(event []byte) error {
+	_, err := fmt.Fprintf(s.out, "%s\n", event)
+	return err
+}
@@ -0,0 +1,138 @@
+package trigger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// resumeTokensCollection 持久化每个被监听集合的 Change Stream resume
+// token，使重启后的监听能够从断点续传，而不会漏掉期间产生的事件。
+const resumeTokensCollection = "_resume_tokens"
+
+// resumeTokenDoc 是 _resume_tokens 集合中的一条记录。
+type resumeTokenDoc struct {
+	Collection string   `bson:"_id"`
+	Token      bson.Raw `bson:"token"`
+}
+
+// Watcher 在给定的若干集合上打开 Change Stream，并把每条 insert 事件
+// 转发给 Sink。
+type Watcher struct {
+	db   *mongo.Database
+	sink Sink
+}
+
+// NewWatcher 创建一个 Watcher。db 必须连接到启用了副本集的 MongoDB 部署，
+// 否则 Watch 会在运行时返回明确的错误。
+func NewWatcher(db *mongo.Database, sink Sink) *Watcher {
+	return &Watcher{db: db, sink: sink}
+}
+
+// WatchCollections 为每个集合启动一个监听 goroutine，阻塞直到 ctx 被取消
+// 或某个监听出现不可恢复的错误。
+func (w *Watcher) WatchCollections(ctx context.Context, collections []string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(collections))
+
+	for _, name := range collections {
+		wg.Add(1)
+		go func(collection string) {
+			defer wg.Done()
+			if err := w.watchOne(ctx, collection); err != nil {
+				errs <- fmt.Errorf("监听集合 %s 失败: %w", collection, err)
+				cancel()
+			}
+		}(name)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		return err
+	}
+	return nil
+}
+
+func (w *Watcher) watchOne(ctx context.Context, collection string) error {
+	coll := w.db.Collection(collection)
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+
+	resumeToken, err := w.loadResumeToken(ctx, collection)
+	if err != nil {
+		return err
+	}
+	if resumeToken != nil {
+		opts.SetResumeAfter(resumeToken)
+	}
+
+	insertsOnly := mongo.Pipeline{{{Key: "$match", Value: bson.D{{Key: "operationType", Value: "insert"}}}}}
+	stream, err := coll.Watch(ctx, insertsOnly, opts)
+	if err != nil {
+		if strings.Contains(err.Error(), "replica set") || strings.Contains(err.Error(), "$changeStream") {
+			return fmt.Errorf("无法打开 Change Stream，该功能要求 MongoDB 以副本集（replica set）模式运行: %w", err)
+		}
+		return err
+	}
+	defer stream.Close(ctx)
+
+	log.Printf("开始监听集合 %s 的变更流", collection)
+
+	for stream.Next(ctx) {
+		var changeEvent bson.M
+		if err := stream.Decode(&changeEvent); err != nil {
+			log.Printf("解码集合 %s 的变更事件失败: %v", collection, err)
+			continue
+		}
+
+		payload, err := json.Marshal(changeEvent)
+		if err != nil {
+			log.Printf("序列化集合 %s 的变更事件失败: %v", collection, err)
+			continue
+		}
+
+		if err := w.sink.Send(payload); err != nil {
+			log.Printf("向 sink 发送集合 %s 的变更事件失败: %v", collection, err)
+			continue
+		}
+
+		if err := w.saveResumeToken(ctx, collection, stream.ResumeToken()); err != nil {
+			log.Printf("持久化集合 %s 的 resume token 失败: %v", collection, err)
+		}
+	}
+
+	return stream.Err()
+}
+
+func (w *Watcher) loadResumeToken(ctx context.Context, collection string) (bson.Raw, error) {
+	var doc resumeTokenDoc
+	err := w.db.Collection(resumeTokensCollection).FindOne(ctx, bson.M{"_id": collection}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 resume token 失败: %w", err)
+	}
+	return doc.Token, nil
+}
+
+func (w *Watcher) saveResumeToken(ctx context.Context, collection string, token bson.Raw) error {
+	_, err := w.db.Collection(resumeTokensCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": collection},
+		bson.M{"$set": bson.M{"token": token}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
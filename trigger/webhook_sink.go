@@ -0,0 +1,35 @@
+package trigger
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WebhookSink 将每条变更事件以 POST 请求发送到指定的 URL。
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink 创建一个指向 url 的 WebhookSink。
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *WebhookSink) Send(event []byte) error {
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(event))
+	if err != nil {
+		return fmt.Errorf("推送 webhook 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook 返回非预期状态码: %d", resp.StatusCode)
+	}
+	return nil
+}